@@ -64,8 +64,10 @@ func handleDatabaseChanges() {
 				for ch := range cl.IterChecks() {
 					if ch.Command().ID() == alert.CommandID {
 						for a := range ch.IterAlerts() {
-							if a.ID() == alert.ID {
-								a.Update(alert)
+							if a.ID() == alert.ID && a.Update(alert) {
+								if err := a.SeedBaselines(natsConn, cl.ID(), alert.CommandID); err != nil {
+									log.WithError(err).WithField("alert_id", a.ID()).Warn("error learning the baselines from the check history")
+								}
 							}
 						}
 						return
@@ -119,7 +121,15 @@ func handleDatabaseChanges() {
 			if cl.ID() == alert.ClientID {
 				for ch := range cl.IterChecks() {
 					if ch.Command().ID() == alert.CommandID {
-						a := models.NewAlert(alert)
+						a, err := models.NewAlert(alert)
+						if err != nil {
+							log.WithError(err).Errorf("error creating alert (%s)", "alert_options.create")
+							return
+						}
+
+						if err := a.SeedBaselines(natsConn, cl.ID(), alert.CommandID); err != nil {
+							log.WithError(err).WithField("alert_id", a.ID()).Warn("error learning the baselines from the check history")
+						}
 
 						al, err := FindWithAlertAndClient(natsConn, a.ID(), cl.ID())
 						if err == nil {
@@ -178,4 +188,125 @@ func handleDatabaseChanges() {
 			manager.RemoveClientByID(cl.ID)
 		}
 	})
+
+	natsConn.Subscribe("contacts.create.after", func(m *nats.Msg) {
+		var contact models.ContactRecord
+		err := bson.UnmarshalJSON(m.Data, &contact)
+		if err != nil {
+			log.WithError(err).Errorf("error decoding event (%s)", "contacts.create")
+			return
+		}
+
+		models.Contacts().UpdateContact(contact)
+	})
+	natsConn.Subscribe("contacts.update.after", func(m *nats.Msg) {
+		var contact models.ContactRecord
+		err := bson.UnmarshalJSON(m.Data, &contact)
+		if err != nil {
+			log.WithError(err).Errorf("error decoding event (%s)", "contacts.update")
+			return
+		}
+
+		// New contacts are added and existing contacts are modified
+		models.Contacts().UpdateContact(contact)
+	})
+	natsConn.Subscribe("contacts.delete.after", func(m *nats.Msg) {
+		var contacts []models.ContactRecord
+		err := bson.UnmarshalJSON(m.Data, &contacts)
+		if err != nil {
+			log.WithError(err).Errorf("error decoding event (%s)", "contacts.delete")
+			return
+		}
+
+		for _, c := range contacts {
+			models.Contacts().RemoveContactByID(c.ID)
+		}
+	})
+
+	natsConn.Subscribe("contact_groups.create.after", func(m *nats.Msg) {
+		var group models.ContactGroupRecord
+		err := bson.UnmarshalJSON(m.Data, &group)
+		if err != nil {
+			log.WithError(err).Errorf("error decoding event (%s)", "contact_groups.create")
+			return
+		}
+
+		models.Contacts().UpdateGroup(group)
+	})
+	natsConn.Subscribe("contact_groups.update.after", func(m *nats.Msg) {
+		var group models.ContactGroupRecord
+		err := bson.UnmarshalJSON(m.Data, &group)
+		if err != nil {
+			log.WithError(err).Errorf("error decoding event (%s)", "contact_groups.update")
+			return
+		}
+
+		// New contact groups are added and existing contact groups are modified
+		models.Contacts().UpdateGroup(group)
+	})
+	natsConn.Subscribe("contact_groups.delete.after", func(m *nats.Msg) {
+		var groups []models.ContactGroupRecord
+		err := bson.UnmarshalJSON(m.Data, &groups)
+		if err != nil {
+			log.WithError(err).Errorf("error decoding event (%s)", "contact_groups.delete")
+			return
+		}
+
+		for _, g := range groups {
+			models.Contacts().RemoveGroupByID(g.ID)
+		}
+	})
+
+	natsConn.Subscribe("acknowledgements.create.after", func(m *nats.Msg) {
+		var ack models.AcknowledgementRecord
+		err := bson.UnmarshalJSON(m.Data, &ack)
+		if err != nil {
+			log.WithError(err).Errorf("error decoding event (%s)", "acknowledgements.create")
+			return
+		}
+
+		a := manager.AlertByID(ack.AlertID)
+		if a == nil || !a.Acknowledge() {
+			log.WithField("alert_id", ack.AlertID).Debug("acknowledged alert is not firing")
+		}
+	})
+	natsConn.Subscribe("acknowledgements.delete.after", func(m *nats.Msg) {
+		var acks []models.AcknowledgementRecord
+		err := bson.UnmarshalJSON(m.Data, &acks)
+		if err != nil {
+			log.WithError(err).Errorf("error decoding event (%s)", "acknowledgements.delete")
+			return
+		}
+
+		for _, ack := range acks {
+			if a := manager.AlertByID(ack.AlertID); a != nil {
+				a.SetAcknowledged(false)
+			}
+		}
+	})
+
+	updateSilence := func(m *nats.Msg) {
+		var silence models.SilenceRecord
+		err := bson.UnmarshalJSON(m.Data, &silence)
+		if err != nil {
+			log.WithError(err).Errorf("error decoding event (%s)", m.Subject)
+			return
+		}
+
+		models.Silences().UpdateSilence(silence)
+	}
+	natsConn.Subscribe("silences.create.after", updateSilence)
+	natsConn.Subscribe("silences.update.after", updateSilence)
+	natsConn.Subscribe("silences.delete.after", func(m *nats.Msg) {
+		var silences []models.SilenceRecord
+		err := bson.UnmarshalJSON(m.Data, &silences)
+		if err != nil {
+			log.WithError(err).Errorf("error decoding event (%s)", "silences.delete")
+			return
+		}
+
+		for _, s := range silences {
+			models.Silences().RemoveSilenceByID(s.ID)
+		}
+	})
 }
@@ -1,57 +1,120 @@
 package models
 
 import (
-	"strconv"
+	"encoding/json"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/keiwi/server/providers"
 	"github.com/keiwi/server/services"
+	"github.com/keiwi/utils"
+	"github.com/keiwi/utils/log"
 	"github.com/keiwi/utils/models"
 	"github.com/nats-io/go-nats"
+	"github.com/pkg/errors"
 	"gopkg.in/mgo.v2/bson"
 )
 
 // NewAlert - will create a new virtual alert based on AlertOptions
-func NewAlert(ao models.AlertOption) *Alert {
+func NewAlert(ao models.AlertOption) (*Alert, error) {
 	alert := &Alert{
 		rw:            new(sync.RWMutex),
+		checking:      new(sync.Mutex),
 		id:            ao.ID,
 		clientid:      ao.ClientID,
 		delay:         ao.Delay,
 		previousalert: time.Time{},
+		flapping:      NewFlapping(),
 	}
 
-	switch ao.Alert {
-	case "cpu":
-		avg, err := strconv.ParseFloat(ao.Value, 64)
+	for severity, value := range parseLevels(ao.Value) {
+		p, err := newProvider(ao.Alert, ao.Count, value)
 		if err != nil {
-			return nil
+			return nil, errors.Wrapf(err, "error creating %s alert %s", severity, ao.ID.Hex())
 		}
-		alert.alert = providers.NewAlertProviderCPU(ao.Count, avg)
+		alert.levels = append(alert.levels, Level{Severity: severity, Provider: p})
 	}
+	sortLevels(alert.levels)
 
-	for _, s := range strings.Split(ao.Service, ",") {
-		switch s {
-		case "sms":
-			alert.services = append(alert.services, services.NewServiceSMS())
-		case "email":
-			// TODO: Implement emails
-		}
-	}
-	return alert
+	alert.services, alert.contactgroups = parseServices(ao.Service)
+	alert.escalation = parseEscalation(ao.Service)
+	return alert, nil
+}
+
+// Alert states published together with the alert record
+const (
+	AlertStateFiring   = "firing"
+	AlertStateResolved = "resolved"
+	AlertStateFlapping = "flapping"
+)
+
+// AlertRecord - is the alert record that is published over nats
+type AlertRecord struct {
+	models.Alert `bson:",inline"`
+	State        string `json:"state" bson:"state"`
+	Severity     string `json:"severity" bson:"severity"`
+}
+
+// dispatcher - queues the notifications of all alerts, the notifications are sent directly without it
+var dispatcher *services.Dispatcher
+
+// throttle - rate limits the notifications of all alerts, nothing is rate limited without it
+var throttle *services.Throttle
+
+// SetThrottle - Will modify the throttle that rate limits the notifications of all alerts
+func SetThrottle(t *services.Throttle) {
+	throttle = t
+}
+
+// templates - render the title and message of the notifications, the alert message is used without them
+var templates *services.Templates
+
+// SetTemplates - Will modify the templates that render the notifications of all alerts
+func SetTemplates(t *services.Templates) {
+	templates = t
+}
+
+// digest - collects the notifications for the services that send digests
+var digest *services.Digest
+
+// SetDigest - Will modify the digest that collects the notifications for the services that send digests
+func SetDigest(d *services.Digest) {
+	digest = d
+}
+
+// quiet - holds back the notifications of the services and contacts in their quiet hours
+var quiet *services.QuietHours
+
+// SetQuietHours - Will modify the quiet hours that are used for the notifications of all alerts
+func SetQuietHours(q *services.QuietHours) {
+	quiet = q
+}
+
+// SetDispatcher - Will modify the dispatcher that queues the notifications of all alerts
+func SetDispatcher(d *services.Dispatcher) {
+	dispatcher = d
 }
 
 // Alert - is the virtual alert struct
 type Alert struct {
 	rw            *sync.RWMutex
+	checking      *sync.Mutex // Serializes Check and Escalate as the check loop, the saved checks and the escalation loop call them
 	id            bson.ObjectId
 	clientid      bson.ObjectId
 	delay         int
-	alert         providers.AlertProvider
+	levels        []Level
 	previousalert time.Time
-	services      []services.Service
+	severity      Severity // The current severity, none when the alert isn't firing
+	peak          Severity // The highest severity since the alert started firing
+	flapping      *Flapping
+	services      []AlertService
+	contactgroups []string // IDs or names of the contact groups that receive the notifications
+	escalation    *EscalationPolicy
+	tier          int       // The amount of escalation tiers that have been notified since the alert started firing
+	firingsince   time.Time // When the alert started firing, the escalation tiers are timed from this
+	acknowledged  bool
 }
 
 // ID - Will return the alert ID
@@ -75,11 +138,21 @@ func (a Alert) Delay() int {
 	return a.delay
 }
 
-// Alert - Will return the AlertProvider
+// Alert - Will return the AlertProvider of the lowest severity, all levels have the same type of provider
 func (a Alert) Alert() providers.AlertProvider {
 	a.rw.RLock()
 	defer a.rw.RUnlock()
-	return a.alert
+	if len(a.levels) == 0 {
+		return nil
+	}
+	return a.levels[0].Provider
+}
+
+// Levels - Will return the alert providers for all severities, ordered from the lowest severity
+func (a Alert) Levels() []Level {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return a.levels
 }
 
 // PreviousAlert - Will return when the previous alert was made
@@ -89,11 +162,48 @@ func (a Alert) PreviousAlert() time.Time {
 	return a.previousalert
 }
 
+// Firing - Will return whether the alert condition is currently met
+func (a Alert) Firing() bool {
+	return a.Severity() != SeverityNone
+}
+
+// Severity - Will return the current severity of the alert
+func (a Alert) Severity() Severity {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return a.severity
+}
+
 // Services - Will return all of the services associated with the alert
 func (a Alert) Services() []services.Service {
 	a.rw.RLock()
 	defer a.rw.RUnlock()
-	return a.services
+	s := make([]services.Service, len(a.services))
+	for i, service := range a.services {
+		s[i] = service.Service
+	}
+	return s
+}
+
+// ContactGroups - Will return the IDs or names of the contact groups that receive the notifications
+func (a Alert) ContactGroups() []string {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return a.contactgroups
+}
+
+// Escalation - Will return the escalation policy of the alert, or nil if it doesn't escalate
+func (a Alert) Escalation() *EscalationPolicy {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return a.escalation
+}
+
+// Acknowledged - Will return whether someone acknowledged the firing alert, acknowledged alerts don't escalate
+func (a Alert) Acknowledged() bool {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return a.acknowledged
 }
 
 // SetID - Will modify the virtual ID
@@ -117,11 +227,17 @@ func (a *Alert) SetDelay(delay int) {
 	a.delay = delay
 }
 
-// SetAlert - Will modify the AlertProvider associated with the alert
+// SetAlert - Will replace all levels with a single critical AlertProvider
 func (a *Alert) SetAlert(alert providers.AlertProvider) {
+	a.SetLevels([]Level{{Severity: SeverityCritical, Provider: alert}})
+}
+
+// SetLevels - Will modify the alert providers for all severities
+func (a *Alert) SetLevels(levels []Level) {
+	sortLevels(levels)
 	a.rw.Lock()
 	defer a.rw.Unlock()
-	a.alert = alert
+	a.levels = levels
 }
 
 // SetPreviousAlert - Will modify when the previous alert was made
@@ -131,85 +247,465 @@ func (a *Alert) SetPreviousAlert(previous time.Time) {
 	a.previousalert = previous
 }
 
-// SetServices - Will modify all of the services associated with the alert
+// SetEscalation - Will modify the escalation policy of the alert
+func (a *Alert) SetEscalation(policy *EscalationPolicy) {
+	a.rw.Lock()
+	defer a.rw.Unlock()
+	a.escalation = policy
+}
+
+// Acknowledge - Will acknowledge the firing alert so it isn't notified about again until it resolves,
+// false is returned if the alert isn't firing
+func (a *Alert) Acknowledge() bool {
+	a.rw.Lock()
+	defer a.rw.Unlock()
+	if a.severity == SeverityNone {
+		return false
+	}
+	a.acknowledged = true
+	return true
+}
+
+// SetAcknowledged - Will modify whether the firing alert is acknowledged, it's reset when the alert resolves
+func (a *Alert) SetAcknowledged(acknowledged bool) {
+	a.rw.Lock()
+	defer a.rw.Unlock()
+	a.acknowledged = acknowledged
+}
+
+// SetSeverity - Will modify the current severity of the alert and keep track of the highest severity until it resolves
+func (a *Alert) SetSeverity(severity Severity) {
+	a.rw.Lock()
+	defer a.rw.Unlock()
+	a.severity = severity
+	if severity == SeverityNone || severity > a.peak {
+		a.peak = severity
+	}
+}
+
+// SetServices - Will modify all of the services associated with the alert, the services are notified about all severities
 func (a *Alert) SetServices(s []services.Service) {
 	a.rw.Lock()
 	defer a.rw.Unlock()
-	a.services = s
+	a.services = make([]AlertService, len(s))
+	for i, service := range s {
+		a.services[i] = AlertService{Severity: SeverityInfo, Name: service.Name(), Service: service}
+	}
 }
 
 // Update - will update the virtual alert data based on AlertOptions
 func (a *Alert) Update(alert models.AlertOption) bool {
-	if alert.Delay != a.Delay() {
-		a.SetDelay(alert.Delay)
-	}
-
-	switch alert.Alert {
-	case "cpu":
-		avg, err := strconv.ParseFloat(alert.Value, 64)
+	// Every level is created before anything is changed so a failed update keeps the alert as it was
+	var levels []Level
+	for severity, value := range parseLevels(alert.Value) {
+		p, err := updateProvider(a.provider(severity), alert.Alert, alert.Count, value)
 		if err != nil {
+			log.WithError(err).Errorf("error updating %s alert %s", severity, alert.ID.Hex())
 			return false
 		}
-		if strings.ToLower(a.Alert().Name()) != alert.Alert {
-			a.SetAlert(providers.NewAlertProviderCPU(alert.Count, avg))
-		} else {
-			if a.Alert().(*providers.CPU).Total != alert.Count {
-				a.Alert().(*providers.CPU).Total = alert.Count
+		levels = append(levels, Level{Severity: severity, Provider: p})
+	}
+	sortLevels(levels)
+	servs, groups := parseServices(alert.Service)
+	escalation := parseEscalation(alert.Service)
+
+	a.rw.Lock()
+	defer a.rw.Unlock()
+	a.delay = alert.Delay
+	a.levels = levels
+	a.services, a.contactgroups = servs, groups
+	a.escalation = escalation
+	return true
+}
+
+// SeedBaselines - Will learn the baselines of the anomaly levels that don't have any history yet from the
+// stored checks of the client and command, so the baselines aren't lost when the alert is created or updated
+func (a *Alert) SeedBaselines(conn *nats.Conn, clientID, commandID bson.ObjectId) error {
+	var anomalies []*providers.Anomaly
+	for _, l := range a.Levels() {
+		if p, ok := l.Provider.(*providers.Anomaly); ok && p.Samples() == 0 {
+			anomalies = append(anomalies, p)
+		}
+	}
+	if len(anomalies) == 0 {
+		return nil
+	}
+
+	checks, err := FindCheck(conn, utils.Filter{"client_id": clientID, "command_id": commandID})
+	if err != nil {
+		return err
+	}
+
+	// The checks are sorted with the newest first
+	for i := len(checks) - 1; i >= 0; i-- {
+		if checks[i].Error {
+			continue
+		}
+		for _, p := range anomalies {
+			p.Learn(checks[i].Response, checks[i].CreatedAt)
+		}
+	}
+	return nil
+}
+
+// provider - Will return the alert provider for a specific severity, or nil if there is none
+func (a Alert) provider(severity Severity) providers.AlertProvider {
+	for _, l := range a.Levels() {
+		if l.Severity == severity {
+			return l.Provider
+		}
+	}
+	return nil
+}
+
+// Check - Will check if an alert should be made or not, no notifications are sent
+// while the alert or the check it belongs to is flapping or the alert is silenced,
+// an acknowledged alert only notifies when it resolves
+func (a *Alert) Check(resp string, conn *nats.Conn, client *Client, check *Check) {
+	// The whole check has to be serialized, the severity is read and written in several steps
+	a.checking.Lock()
+	defer a.checking.Unlock()
+
+	levels := a.Levels()
+	if len(levels) == 0 {
+		return
+	}
+
+	for _, l := range levels {
+		if d, ok := l.Provider.(*providers.Deadman); ok {
+			d.Update(check.LastSuccess(), time.Duration(check.Command().Interval())*time.Second, client.Conn() != nil)
+		}
+	}
+
+	a.rw.RLock()
+	previous, peak := a.severity, a.peak
+	a.rw.RUnlock()
+	replied := !check.Error() && json.Valid([]byte(resp))
+	severity, al := evaluateLevels(levels, resp, replied, previous)
+
+	firing := severity != SeverityNone
+	wasFiring := previous != SeverityNone
+	if firing != wasFiring {
+		a.flapping.Change()
+	}
+	a.SetSeverity(severity)
+
+	flapping, changed := a.flapping.Update()
+	if changed {
+		a.NotifyFlapping(client, check, al.Name(), flapping)
+	}
+	flapping = flapping || check.Flapping().Flapping()
+	silenced := Silences().Silenced(client, check, a)
+
+	if firing {
+		// Alerts are checked on every loop for deadman alerts, so a firing alert is only
+		// repeated after the delay while a new or changed severity is sent directly
+		next := a.PreviousAlert().Add(time.Duration(a.Delay()) * time.Second)
+		if severity == previous && time.Now().Before(next) {
+			return
+		}
+
+		if !flapping && !silenced && !a.Acknowledged() {
+			// When the severity is lowered the services notified about the previous severity are told as well
+			target := severity
+			if previous > target {
+				target = previous
 			}
-			if a.Alert().(*providers.CPU).Avg != avg {
-				a.Alert().(*providers.CPU).Avg = avg
+			a.notify(client, check, target, AlertStateFiring, al, fmt.Sprintf("[%s] %s", strings.ToUpper(severity.String()), al.Message()))
+		}
+
+		createdAt, err := a.publish(conn, "alerts.create.send", AlertStateFiring, severity, al.Value())
+		if err != nil {
+			log.WithError(err).WithField("alert_id", a.ID()).Error("error publishing alert")
+		}
+		a.SetPreviousAlert(createdAt)
+		if !wasFiring {
+			a.startFiring(createdAt)
+		}
+	} else if wasFiring {
+		if p := a.provider(previous); p != nil {
+			al = p
+		}
+		tiers := a.stopFiring()
+		if !flapping && !silenced {
+			// Everyone that was notified while the alert was firing is told that it's resolved
+			msg := fmt.Sprintf("Resolved: %s", al.Message())
+			a.notify(client, check, peak, AlertStateResolved, al, msg)
+			for _, t := range tiers {
+				a.notifyTier(client, check, t, peak, AlertStateResolved, al, msg)
 			}
 		}
+
+		if _, err := a.publish(conn, "alerts.resolve.send", AlertStateResolved, previous, al.Value()); err != nil {
+			log.WithError(err).WithField("alert_id", a.ID()).Error("error publishing resolved alert")
+		}
 	}
+}
 
+// startFiring - Will start the escalation from the first tier
+func (a *Alert) startFiring(since time.Time) {
 	a.rw.Lock()
 	defer a.rw.Unlock()
-	a.services = []services.Service{}
-	for _, s := range strings.Split(alert.Service, ",") {
-		switch s {
-		case "sms":
-			a.services = append(a.services, services.NewServiceSMS())
-		case "email":
-			// TODO: Implement this
-		}
+	a.firingsince = since
+	a.tier = 0
+	a.acknowledged = false
+}
+
+// stopFiring - Will stop the escalation and return the tiers that were notified
+func (a *Alert) stopFiring() []EscalationTier {
+	a.rw.Lock()
+	defer a.rw.Unlock()
+	var tiers []EscalationTier
+	if a.escalation != nil && a.tier <= len(a.escalation.Tiers) {
+		tiers = a.escalation.Tiers[:a.tier]
 	}
-	return true
+	a.firingsince = time.Time{}
+	a.tier = 0
+	a.acknowledged = false
+	return tiers
 }
 
-// Check - Will check if an alert should be made or not
-func (a *Alert) Check(resp string, conn *nats.Conn) {
+// Escalate - Will notify the escalation tiers that are due, the tiers are timed from when the alert
+// started firing and the alert only escalates while it's firing, not flapping and not acknowledged
+func (a *Alert) Escalate(client *Client, check *Check) {
+	// Serialized with Check so an alert that resolves can't miss a tier that is being notified,
+	// the resolved notification is sent to every tier that was notified before it
+	a.checking.Lock()
+	defer a.checking.Unlock()
+
 	a.rw.RLock()
-	al := a.alert
+	policy, tier, since, severity, acknowledged := a.escalation, a.tier, a.firingsince, a.severity, a.acknowledged
 	a.rw.RUnlock()
-	if al.Check(resp) {
-		if time.Now().After(a.PreviousAlert()) {
-			for s := range a.IterServices() {
-				s.Send(al.Name(), al.Message())
-			}
+	if policy == nil || severity == SeverityNone || since.IsZero() || acknowledged {
+		return
+	}
+	if a.flapping.Flapping() || check.Flapping().Flapping() || Silences().Silenced(client, check, a) {
+		return
+	}
+
+	al := a.provider(severity)
+	if al == nil {
+		return
+	}
+
+	next := tier
+	for ; next < len(policy.Tiers) && time.Since(since) >= policy.Tiers[next].After; next++ {
+		msg := fmt.Sprintf("[%s] Escalated to tier %d: %s", strings.ToUpper(severity.String()), next+1, al.Message())
+		a.notifyTier(client, check, policy.Tiers[next], severity, AlertStateFiring, al, msg)
+	}
+
+	a.rw.Lock()
+	defer a.rw.Unlock()
+	a.tier = next
+}
+
+// NotifyFlapping - Will notify all services that the subject (the alert or its check) started or stopped flapping,
+// the services for the lowest severity are notified as that is the severity the alert starts firing at
+func (a *Alert) NotifyFlapping(client *Client, check *Check, subject string, flapping bool) {
+	levels := a.Levels()
+	if len(levels) == 0 || Silences().Silenced(client, check, a) {
+		return
+	}
+
+	l := levels[0]
+	state, msg := flappingMessage(subject, flapping, a.Firing())
+	a.notify(client, check, l.Severity, state, l.Provider, msg)
+}
+
+// flappingMessage - Will return the state and message of a notification about flapping that started or stopped
+func flappingMessage(subject string, flapping, firing bool) (string, string) {
+	if flapping {
+		return AlertStateFlapping, fmt.Sprintf("Flapping started: %s is changing state too often, notifications are paused", subject)
+	}
+
+	state := AlertStateResolved
+	if firing {
+		state = AlertStateFiring
+	}
+	return state, fmt.Sprintf("Flapping stopped: %s is stable again, the alert is %s", subject, state)
+}
+
+// notify - Will send a notification to all of the services associated with the alert for the severity
+func (a *Alert) notify(client *Client, check *Check, severity Severity, state string, al providers.AlertProvider, msg string) {
+	n := a.notification(client, check, severity, state, al, msg)
+	n.Contacts = Contacts().Recipients(a.ContactGroups())
+	a.send(n, servicesFor(a.alertServices(), severity))
+}
+
+// notifyTier - Will send a notification to the services of an escalation tier for the severity, the
+// contact groups of the alert are used if the tier doesn't have its own
+func (a *Alert) notifyTier(client *Client, check *Check, tier EscalationTier, severity Severity, state string, al providers.AlertProvider, msg string) {
+	groups := tier.ContactGroups
+	if len(groups) == 0 {
+		groups = a.ContactGroups()
+	}
+
+	n := a.notification(client, check, severity, state, al, msg)
+	n.Contacts = Contacts().Recipients(groups)
+
+	a.send(n, servicesFor(tier.Services, severity))
+}
+
+// notification - Will create the notification about the alert that is sent to the services
+func (a *Alert) notification(client *Client, check *Check, severity Severity, state string, al providers.AlertProvider, msg string) services.Notification {
+	var threshold string
+	if t, ok := al.(providers.ThresholdProvider); ok {
+		threshold = t.Threshold()
+	}
+
+	a.rw.RLock()
+	since := a.firingsince
+	a.rw.RUnlock()
+
+	return services.Notification{
+		AlertID:   a.ID(),
+		ClientID:  client.ID(),
+		ClientIP:  client.IP(),
+		Command:   check.Command().Command(),
+		Group:     groupName(check),
+		Provider:  al.Name(),
+		Title:     al.Name(),
+		Message:   msg,
+		Value:     al.Value(),
+		Threshold: threshold,
+		Severity:  severity.String(),
+		State:     state,
+		Timestamp: time.Now(),
+		Since:     since,
+	}
+}
+
+// groupName - Will return the name of the group the check belongs to
+func groupName(check *Check) string {
+	if g := check.Group(); g != nil {
+		return g.Name()
+	}
+	return ""
+}
+
+// send - Will send the notification to the services, unless it's rate limited or the service
+// collects the notifications into a digest
+func (a *Alert) send(notification services.Notification, s []AlertService) {
+	for _, service := range s {
+		deliveries := []services.QuietDelivery{{Service: service.Name, Notification: notification}}
+		if quiet != nil {
+			deliveries = quiet.Apply(service.Name, notification, time.Now())
 		}
 
-		alert := models.Alert{
-			AlertID:  a.ID(),
-			ClientID: a.ClientID(),
-			Value:    al.Value(),
+		// Services in their quiet hours can reroute the notification to another service
+		for _, d := range deliveries {
+			s := service.Service
+			if d.Service != service.Name {
+				rerouted, err := NewService(d.Service)
+				if err != nil {
+					log.WithError(err).WithField("service", d.Service).Error("error creating service to reroute to")
+					continue
+				}
+				s = rerouted
+			}
+			deliver(d.Service, s, d.Notification, d.At)
 		}
-		alert.UpdatedAt = time.Now()
-		alert.CreatedAt = time.Now()
+	}
+}
 
-		data, err := bson.MarshalJSON(alert)
+// deliver - Will render the notification for the service and send it at the specific time, unless
+// it's rate limited or the service collects the notifications into a digest
+func deliver(name string, s services.Service, n services.Notification, at time.Time) {
+	if templates != nil {
+		rendered, err := templates.Render(name, n)
 		if err != nil {
-			a.SetPreviousAlert(time.Now().Add(time.Duration(a.delay) * time.Second))
+			log.WithError(err).WithField("service", name).Error("error rendering notification, using the default message")
+		} else {
+			n = rendered
+		}
+	}
+
+	if throttle != nil {
+		var ok bool
+		if n, ok = throttle.Allow(name, n); !ok {
+			log.WithFields(log.Fields{
+				"alert_id": n.AlertID,
+				"service":  name,
+			}).Debug("notification suppressed by rate limit")
 			return
 		}
+	}
 
-		err = conn.Publish("alerts.create.send", data)
-		if err != nil {
-			a.SetPreviousAlert(time.Now().Add(time.Duration(a.delay) * time.Second))
+	if at.IsZero() && digest != nil && digest.Add(name, s, n) {
+		return
+	}
+	deliverAt(name, s, n, at)
+}
+
+// Deliver - Will queue the notification for the service, the notification is sent directly
+// when there is no dispatcher or it couldn't be queued
+func Deliver(name string, s services.Service, n services.Notification) {
+	deliverAt(name, s, n, time.Time{})
+}
+
+// deliverAt - Will queue the notification for the service so it's sent at the specific time, a zero
+// time sends it as soon as possible. Without a dispatcher the notification is only kept in memory
+func deliverAt(name string, s services.Service, n services.Notification, at time.Time) {
+	if dispatcher != nil {
+		err := dispatcher.EnqueueAt(name, s, n, at)
+		if err == nil {
 			return
 		}
+		log.WithError(err).WithField("service", name).Error("error queueing notification, sending it directly")
+	}
+
+	if wait := time.Until(at); wait > 0 {
+		time.AfterFunc(wait, func() {
+			deliverAt(name, s, n, time.Time{})
+		})
+		return
+	}
+
+	if err := s.Send(n); err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"alert_id": n.AlertID,
+			"service":  name,
+		}).Error("error sending notification")
+	}
+}
+
+// alertServices - Will return the services with their severities
+func (a Alert) alertServices() []AlertService {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return a.services
+}
+
+// servicesFor - Will return the services that should be notified about a severity
+func servicesFor(s []AlertService, severity Severity) []AlertService {
+	var filtered []AlertService
+	for _, service := range s {
+		if service.Severity <= severity {
+			filtered = append(filtered, service)
+		}
+	}
+	return filtered
+}
+
+// publish - Will publish an alert record over nats and return when it was created
+func (a *Alert) publish(conn *nats.Conn, subject, state string, severity Severity, value string) (time.Time, error) {
+	alert := AlertRecord{
+		Alert: models.Alert{
+			AlertID:  a.ID(),
+			ClientID: a.ClientID(),
+			Value:    value,
+		},
+		State:    state,
+		Severity: severity.String(),
+	}
+	alert.UpdatedAt = time.Now()
+	alert.CreatedAt = time.Now()
 
-		a.SetPreviousAlert(alert.CreatedAt)
+	data, err := bson.MarshalJSON(alert)
+	if err != nil {
+		return alert.CreatedAt, err
 	}
+	return alert.CreatedAt, conn.Publish(subject, data)
 }
 
 // ServicesLength - Will return the amount of services associated with the alert
@@ -226,7 +722,23 @@ func (a Alert) IterServices() <-chan services.Service {
 		a.rw.RLock()
 		defer a.rw.RUnlock()
 		for _, service := range a.services {
-			ch <- service
+			ch <- service.Service
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// IterServicesFor - Will return a channel and loop through all the services that should be notified about a severity
+func (a Alert) IterServicesFor(severity Severity) <-chan services.Service {
+	ch := make(chan services.Service, a.ServicesLength())
+	go func() {
+		a.rw.RLock()
+		defer a.rw.RUnlock()
+		for _, service := range a.services {
+			if service.Severity <= severity {
+				ch <- service.Service
+			}
 		}
 		close(ch)
 	}()
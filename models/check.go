@@ -4,6 +4,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/keiwi/server/providers"
 	"github.com/keiwi/utils/models"
 	"gopkg.in/mgo.v2/bson"
 )
@@ -11,8 +12,9 @@ import (
 // NewCheck - Creates a new virtual check
 func NewCheck(ch *models.Check, command *Command) *Check {
 	check := &Check{
-		rw:      new(sync.RWMutex),
-		command: command,
+		rw:       new(sync.RWMutex),
+		command:  command,
+		flapping: NewFlapping(),
 	}
 
 	if ch == nil {
@@ -24,6 +26,9 @@ func NewCheck(ch *models.Check, command *Command) *Check {
 	check.err = ch.Error
 	check.finished = ch.Finished
 	check.nexttimestamp = ch.CreatedAt
+	if !ch.Error {
+		check.lastsuccess = ch.CreatedAt
+	}
 	return check
 }
 
@@ -35,9 +40,11 @@ type Check struct {
 	alerts        []*Alert
 	pastid        bson.ObjectId
 	nexttimestamp time.Time
+	lastsuccess   time.Time
 	checked       bool
 	err           bool
 	finished      bool
+	flapping      *Flapping
 }
 
 // Command returns the command
@@ -75,6 +82,13 @@ func (c *Check) Timestamp() (timestamp time.Time) {
 	return c.nexttimestamp
 }
 
+// LastSuccess returns when the check last got a successful response
+func (c *Check) LastSuccess() (timestamp time.Time) {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+	return c.lastsuccess
+}
+
 // Checked returns whether or not the check has been checked.
 func (c *Check) Checked() (checked bool) {
 	c.rw.RLock()
@@ -96,6 +110,13 @@ func (c *Check) Finished() (finished bool) {
 	return c.finished
 }
 
+// Flapping returns the flapping detector for the checks error state
+func (c *Check) Flapping() *Flapping {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+	return c.flapping
+}
+
 // SetGroup modifies the group that this check belongs to
 func (c *Check) SetGroup(g *Group) {
 	c.rw.Lock()
@@ -117,6 +138,13 @@ func (c *Check) SetTimestamp(t time.Time) {
 	c.nexttimestamp = t
 }
 
+// SetLastSuccess modifies when the check last got a successful response
+func (c *Check) SetLastSuccess(t time.Time) {
+	c.rw.Lock()
+	defer c.rw.Unlock()
+	c.lastsuccess = t
+}
+
 // SetChecked modifies whether the check has been checked or not
 func (c *Check) SetChecked(checked bool) {
 	c.rw.Lock()
@@ -128,6 +156,9 @@ func (c *Check) SetChecked(checked bool) {
 func (c *Check) SetError(err bool) {
 	c.rw.Lock()
 	defer c.rw.Unlock()
+	if c.err != err {
+		c.flapping.Change()
+	}
 	c.err = err
 }
 
@@ -178,3 +209,45 @@ func (c Check) IterAlerts() <-chan *Alert {
 	}()
 	return ch
 }
+
+// NotifyFlapping - Will notify the services of the alerts on the check that the check started or stopped flapping,
+// a service that is used by several alerts is only notified once. The services for the lowest severity of each
+// alert are notified as that is the severity the alert starts firing at
+func (c *Check) NotifyFlapping(client *Client, flapping bool) {
+	var (
+		first    *Alert
+		al       providers.AlertProvider
+		severity Severity
+		firing   bool
+		s        []AlertService
+		groups   []string
+	)
+	seen := map[string]bool{}
+	for a := range c.IterAlerts() {
+		levels := a.Levels()
+		if len(levels) == 0 || Silences().Silenced(client, c, a) {
+			continue
+		}
+		if first == nil || levels[0].Severity < severity {
+			first, al, severity = a, levels[0].Provider, levels[0].Severity
+		}
+		firing = firing || a.Firing()
+		for _, service := range servicesFor(a.alertServices(), levels[0].Severity) {
+			if !seen[service.Name] {
+				seen[service.Name] = true
+				s = append(s, service)
+			}
+		}
+		groups = append(groups, a.ContactGroups()...)
+	}
+	if first == nil {
+		return
+	}
+
+	state, msg := flappingMessage(c.Command().Command(), flapping, firing)
+	n := first.notification(client, c, severity, state, al, msg)
+	// The notification is about the check and not a single alert
+	n.AlertID = ""
+	n.Contacts = Contacts().Recipients(groups)
+	first.send(n, s)
+}
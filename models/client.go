@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/keiwi/server/providers"
 	"github.com/keiwi/utils/log"
 	"github.com/keiwi/utils/models"
 	"github.com/nats-io/go-nats"
@@ -154,6 +155,16 @@ func (c *Client) SetConn(conn net.Conn) {
 	c.conn = conn
 }
 
+// closeConn closes the connection after a failed message so the client is seen as disconnected until it connects again
+func (c *Client) closeConn() {
+	c.rw.Lock()
+	defer c.rw.Unlock()
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
 // IterGroups will return a channel and loop through all the groups in a safe way and pass it to the channel
 func (c Client) IterGroups() <-chan *Group {
 	ch := make(chan *Group, c.GroupsLength())
@@ -318,7 +329,7 @@ func (c *Client) RemoveCheckByGroupID(id bson.ObjectId) (ok bool) {
 func (c Client) SendMessage(message string) (resp string, err error) {
 	// Send the message to the client
 	if _, err = fmt.Fprintln(c.conn, message); err != nil {
-		return "", nil
+		return "", err
 	}
 
 	// Read the reply
@@ -332,6 +343,7 @@ func (c Client) SendMessage(message string) (resp string, err error) {
 }
 
 var re = regexp.MustCompile("-port=\"?([\\d,-]+)\"?") // special case check, when checking for port when pinging a server
+var errorRe = regexp.MustCompile(`"error":\s*"[^"]`)  // the reply contains a non empty error message
 
 // SendCheck will send a check to the client and then save the check to the database
 func (c *Client) SendCheck(conn *nats.Conn, check *Check) string {
@@ -386,14 +398,20 @@ func (c *Client) SendCheck(conn *nats.Conn, check *Check) string {
 			resp = fmt.Sprintf(`{"error":"%s","ports":%s}`, e, b)
 		} else {
 			resp, err = c.SendMessage("ping")
+			if err != nil {
+				c.closeConn()
+			}
 		}
 	} else {
 		// Send the command to the client and wait for a reply
 		resp, err = c.SendMessage(command.Command())
+		if err != nil {
+			c.closeConn()
+		}
 	}
 
 	// Check if there was an error in the connection or if the reply contains an error message
-	if err != nil || strings.Contains(resp, `"error":""`) {
+	if err != nil || errorRe.MatchString(resp) {
 		// An error occured so put the check error to true and if it was a connection issue, set the response to the error message
 		check.SetError(true)
 		if err != nil {
@@ -436,13 +454,51 @@ func (c *Client) SaveCheck(conn *nats.Conn, check *Check, resp string) {
 	check.SetTimestamp(createdAt)
 	check.SetID(ch.ID)
 
-	if !check.Error() && command.FailOnError() {
-		c.ResetCheck(check.Group().Name())
+	if !check.Error() {
+		check.SetLastSuccess(ch.CreatedAt)
+		if command.FailOnError() {
+			c.ResetCheck(check.Group().Name())
+		}
 	}
 
+	if flapping, changed := check.Flapping().Update(); changed {
+		check.NotifyFlapping(c, flapping)
+	}
 	for a := range check.IterAlerts() {
-		a.Check(resp, conn)
+		c.checkAlert(conn, check, a, resp)
+	}
+}
+
+// checkAlert will update alerts that depends on the client state and then check the alert
+func (c *Client) checkAlert(conn *nats.Conn, check *Check, a *Alert, resp string) {
+	for _, l := range a.Levels() {
+		if composite, ok := l.Provider.(*providers.Composite); ok {
+			composite.Update(c.States())
+		}
 	}
+	a.Check(resp, conn, c, check)
+}
+
+// Escalate will notify the escalation tiers that are due for all of the firing alerts on the client
+func (c *Client) Escalate() {
+	for check := range c.IterChecks() {
+		for a := range check.IterAlerts() {
+			a.Escalate(c, check)
+		}
+	}
+}
+
+// States returns the state of all alerts and checks on the client, used by composite alerts.
+// Alerts are keyed as "alert:<alert id>" and checks as "check:<command id>"
+func (c Client) States() map[string]bool {
+	states := map[string]bool{}
+	for ch := range c.IterChecks() {
+		states["check:"+ch.Command().ID().Hex()] = ch.Error()
+		for a := range ch.IterAlerts() {
+			states["alert:"+a.ID().Hex()] = a.Firing()
+		}
+	}
+	return states
 }
 
 // ResetCheck will set error and checked to false on all checks with a specific name for the client
@@ -457,7 +513,18 @@ func (c *Client) ResetCheck(name string) {
 
 // StartCheck loop through all clients check and check if it's time to do any checks.
 func (c *Client) StartCheck(conn *nats.Conn) {
-	if c.Conn() == nil {
+	// Deadman and composite alerts has to be checked even when the client doesn't answer
+	connected := c.Conn() != nil
+	for check := range c.IterChecks() {
+		for a := range check.IterAlerts() {
+			switch a.Alert().(type) {
+			case *providers.Deadman, *providers.Composite:
+				c.checkAlert(conn, check, a, "")
+			}
+		}
+	}
+
+	if !connected {
 		return
 	}
 
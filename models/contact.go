@@ -0,0 +1,142 @@
+package models
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keiwi/server/services"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ContactRecord - is the contact as it's stored in the database
+type ContactRecord struct {
+	ID        bson.ObjectId `json:"_id" bson:"_id,omitempty"`
+	Name      string        `json:"name" bson:"name"`
+	Phone     string        `json:"phone" bson:"phone"`
+	Email     string        `json:"email" bson:"email"`
+	Chat      string        `json:"chat" bson:"chat"`
+	CreatedAt time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+// ContactGroupRecord - is the contact group as it's stored in the database
+type ContactGroupRecord struct {
+	ID         bson.ObjectId   `json:"_id" bson:"_id,omitempty"`
+	Name       string          `json:"name" bson:"name"`
+	ContactIDs []bson.ObjectId `json:"contact_ids" bson:"contact_ids"`
+	CreatedAt  time.Time       `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at" bson:"updated_at"`
+}
+
+// NewContactBook - Will create an empty contact book
+func NewContactBook() *ContactBook {
+	return &ContactBook{
+		rw:       new(sync.RWMutex),
+		contacts: map[bson.ObjectId]ContactRecord{},
+		groups:   map[bson.ObjectId]ContactGroupRecord{},
+	}
+}
+
+// contacts - is the contact book used by all alerts to find the recipients of their contact groups
+var contacts = NewContactBook()
+
+// Contacts - Will return the contact book that is used by the alerts
+func Contacts() *ContactBook {
+	return contacts
+}
+
+// ContactBook - contains all of the contacts and contact groups
+type ContactBook struct {
+	rw       *sync.RWMutex
+	contacts map[bson.ObjectId]ContactRecord
+	groups   map[bson.ObjectId]ContactGroupRecord
+}
+
+// SetContacts - Will replace all of the contacts and contact groups
+func (b *ContactBook) SetContacts(contacts []ContactRecord, groups []ContactGroupRecord) {
+	b.rw.Lock()
+	defer b.rw.Unlock()
+	b.contacts = map[bson.ObjectId]ContactRecord{}
+	for _, c := range contacts {
+		b.contacts[c.ID] = c
+	}
+	b.groups = map[bson.ObjectId]ContactGroupRecord{}
+	for _, g := range groups {
+		b.groups[g.ID] = g
+	}
+}
+
+// Contact - Will return the contact with the specific ID
+func (b ContactBook) Contact(id bson.ObjectId) (ContactRecord, bool) {
+	b.rw.RLock()
+	defer b.rw.RUnlock()
+	c, ok := b.contacts[id]
+	return c, ok
+}
+
+// Group - Will return the contact group with the specific ID or name
+func (b ContactBook) Group(ref string) (ContactGroupRecord, bool) {
+	b.rw.RLock()
+	defer b.rw.RUnlock()
+	if bson.IsObjectIdHex(ref) {
+		if g, ok := b.groups[bson.ObjectIdHex(ref)]; ok {
+			return g, true
+		}
+	}
+	for _, g := range b.groups {
+		if strings.EqualFold(g.Name, ref) {
+			return g, true
+		}
+	}
+	return ContactGroupRecord{}, false
+}
+
+// UpdateContact - Will add the contact or modify it if it already exists
+func (b *ContactBook) UpdateContact(contact ContactRecord) {
+	b.rw.Lock()
+	defer b.rw.Unlock()
+	b.contacts[contact.ID] = contact
+}
+
+// RemoveContactByID - Will remove a contact, the contact groups will skip it from now on
+func (b *ContactBook) RemoveContactByID(id bson.ObjectId) {
+	b.rw.Lock()
+	defer b.rw.Unlock()
+	delete(b.contacts, id)
+}
+
+// UpdateGroup - Will add the contact group or modify it if it already exists
+func (b *ContactBook) UpdateGroup(group ContactGroupRecord) {
+	b.rw.Lock()
+	defer b.rw.Unlock()
+	b.groups[group.ID] = group
+}
+
+// RemoveGroupByID - Will remove a contact group
+func (b *ContactBook) RemoveGroupByID(id bson.ObjectId) {
+	b.rw.Lock()
+	defer b.rw.Unlock()
+	delete(b.groups, id)
+}
+
+// Recipients - Will return every contact in the contact groups, referenced by ID or name, only once
+func (b ContactBook) Recipients(groups []string) []services.Contact {
+	var recipients []services.Contact
+	seen := map[bson.ObjectId]bool{}
+	for _, ref := range groups {
+		g, ok := b.Group(ref)
+		if !ok {
+			continue
+		}
+		for _, id := range g.ContactIDs {
+			c, ok := b.Contact(id)
+			if !ok || seen[id] {
+				continue
+			}
+			seen[id] = true
+			recipients = append(recipients, services.Contact{Name: c.Name, Phone: c.Phone, Email: c.Email, Chat: c.Chat})
+		}
+	}
+	return recipients
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/keiwi/server/providers"
+	"github.com/keiwi/utils/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// escalationConfig - is a single tier of an escalation policy as it's written in the server config
+type escalationConfig struct {
+	After    int    `mapstructure:"after"`    // In minutes since the alert started firing
+	Services string `mapstructure:"services"` // Written like the services of an alert option
+}
+
+// EscalationTier - is a set of services that are notified when an alert has been firing for too long
+type EscalationTier struct {
+	After         time.Duration
+	Services      []AlertService
+	ContactGroups []string
+}
+
+// EscalationPolicy - is an ordered list of tiers that are notified until the alert is acknowledged or resolved
+type EscalationPolicy struct {
+	Name  string
+	Tiers []EscalationTier
+}
+
+// LoadEscalationPolicy - Will read the escalation policy with the specific name from the "escalations" key in
+// the server config, every tier is written like {"after": 15, "services": "sms:oncall;contacts=ops"}
+func LoadEscalationPolicy(name string) (*EscalationPolicy, error) {
+	key := "escalations." + name
+	if !viper.IsSet(key) {
+		return nil, fmt.Errorf("unknown escalation policy: %s", name)
+	}
+
+	var tiers []escalationConfig
+	if err := viper.UnmarshalKey(key, &tiers); err != nil {
+		return nil, errors.Wrapf(err, "error reading escalation policy %s", name)
+	}
+
+	policy := &EscalationPolicy{Name: name}
+	var after time.Duration
+	for i, t := range tiers {
+		tier := EscalationTier{After: time.Duration(t.After) * time.Minute}
+		if tier.After < after {
+			return nil, fmt.Errorf("tier %d of escalation policy %s is before the previous tier", i+1, name)
+		}
+		after = tier.After
+
+		tier.Services, tier.ContactGroups = parseServices(t.Services)
+		if len(tier.Services) == 0 {
+			return nil, fmt.Errorf("tier %d of escalation policy %s has no services", i+1, name)
+		}
+		policy.Tiers = append(policy.Tiers, tier)
+	}
+	return policy, nil
+}
+
+// parseEscalation - Will load the escalation policy referenced in the services of an alert option
+// like "email;escalation=oncall", nil is returned if there is none or it couldn't be loaded
+func parseEscalation(value string) *EscalationPolicy {
+	_, options := providers.ParseOptions(value)
+	name := options["escalation"]
+	if name == "" {
+		return nil
+	}
+
+	policy, err := LoadEscalationPolicy(name)
+	if err != nil {
+		log.WithError(err).Error("error loading escalation policy")
+		return nil
+	}
+	return policy
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// NewFlapping - Creates a new flapping detector
+func NewFlapping() *Flapping {
+	return &Flapping{rw: new(sync.RWMutex)}
+}
+
+// Flapping - Keeps track of when a state changed, a state is flapping when it changes
+// more then "flapping_changes" times within "flapping_window" seconds
+type Flapping struct {
+	rw       *sync.RWMutex
+	changes  []time.Time
+	flapping bool
+}
+
+// Flapping - Will return whether the state was flapping at the last update
+func (f Flapping) Flapping() bool {
+	f.rw.RLock()
+	defer f.rw.RUnlock()
+	return f.flapping
+}
+
+// Change - Will record a state change
+func (f *Flapping) Change() {
+	f.rw.Lock()
+	defer f.rw.Unlock()
+	f.changes = append(f.changes, time.Now())
+}
+
+// Update - Will remove the changes outside of the window and return whether the state is flapping
+// and if it started or stopped flapping since the last update
+func (f *Flapping) Update() (flapping bool, changed bool) {
+	limit := viper.GetInt("flapping_changes")
+	window := time.Duration(viper.GetInt("flapping_window")) * time.Second
+
+	f.rw.Lock()
+	defer f.rw.Unlock()
+
+	start := time.Now().Add(-window)
+	i := 0
+	for i < len(f.changes) && f.changes[i].Before(start) {
+		i++
+	}
+	f.changes = f.changes[i:]
+
+	flapping = limit > 0 && len(f.changes) > limit
+	changed = flapping != f.flapping
+	f.flapping = flapping
+	return flapping, changed
+}
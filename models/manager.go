@@ -41,6 +41,20 @@ func (c Manager) ClientByID(id bson.ObjectId) (cl *Client) {
 	return
 }
 
+// AlertByID - Check all the clients for a specific alert based on the alert ID
+func (c Manager) AlertByID(id bson.ObjectId) (a *Alert) {
+	for cl := range c.IterClients() {
+		for ch := range cl.IterChecks() {
+			for al := range ch.IterAlerts() {
+				if al.ID() == id {
+					a = al
+				}
+			}
+		}
+	}
+	return
+}
+
 // AddClient - Add a new client to in memory array
 func (c *Manager) AddClient(client *Client) {
 	c.rw.Lock()
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/keiwi/server/providers"
+)
+
+// newProvider - Will create a new alert provider based on the alert type, count and value
+func newProvider(name string, count int, value string) (providers.AlertProvider, error) {
+	switch name {
+	case "cpu":
+		avg, h, err := providers.ParseThreshold(value)
+		if err != nil {
+			return nil, err
+		}
+		agg, err := providers.ParseAggregation(value)
+		if err != nil {
+			return nil, err
+		}
+		return providers.NewAlertProviderCPU(count, avg, agg, h), nil
+	case "memory", "swap":
+		avg, h, err := providers.ParseThreshold(value)
+		if err != nil {
+			return nil, err
+		}
+		agg, err := providers.ParseAggregation(value)
+		if err != nil {
+			return nil, err
+		}
+		return providers.NewAlertProviderMemory(count, avg, name == "swap", agg, h), nil
+	case "disk":
+		return providers.NewAlertProviderDisk(value)
+	case "port":
+		return providers.NewAlertProviderPort(count, value)
+	case "json":
+		return providers.NewAlertProviderJSON(count, value)
+	case "deadman":
+		return providers.NewAlertProviderDeadman(count, value)
+	case "rate":
+		return providers.NewAlertProviderRate(count, value)
+	case "composite":
+		return providers.NewAlertProviderComposite(value)
+	case "anomaly":
+		// Every alert belongs to one client and command so the baseline is learned per client and command
+		return providers.NewAlertProviderAnomaly(value)
+	}
+	return nil, fmt.Errorf("unknown alert type: %s", name)
+}
+
+// updateProvider - Will create the alert provider for an updated alert, the history of the existing
+// provider is kept if the alert type is the same. The existing provider isn't modified so it can
+// still be used until the new provider replaces it
+func updateProvider(p providers.AlertProvider, name string, count int, value string) (providers.AlertProvider, error) {
+	np, err := newProvider(name, count, value)
+	if err != nil {
+		return nil, err
+	}
+	if p != nil && strings.ToLower(p.Name()) == name {
+		providers.KeepHistory(np, p)
+	}
+	return np, nil
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/keiwi/server/providers"
+	"github.com/keiwi/server/services"
+	"github.com/keiwi/utils/log"
+	"github.com/pkg/errors"
+)
+
+// Severity - is how severe an alert is, a higher value is more severe
+type Severity int
+
+// Severity levels, an alert without any severities in its value is critical
+const (
+	SeverityNone Severity = iota
+	SeverityInfo
+	SeverityWarning
+	SeverityCritical
+)
+
+// String - Will return the name of the severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	}
+	return ""
+}
+
+// ParseSeverity - Will return the severity with the specific name
+func ParseSeverity(name string) (Severity, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "info":
+		return SeverityInfo, true
+	case "warning":
+		return SeverityWarning, true
+	case "critical":
+		return SeverityCritical, true
+	}
+	return SeverityNone, false
+}
+
+// Level - is the alert provider for a single severity of an alert
+type Level struct {
+	Severity Severity
+	Provider providers.AlertProvider
+}
+
+// AlertService - is a service and the lowest severity that it should be notified about
+type AlertService struct {
+	Severity Severity
+	Name     string // The name the service was created from, like "sms:oncall"
+	Service  services.Service
+}
+
+var severityRe = regexp.MustCompile(`(?:^|\|)\s*(info|warning|critical):`)
+
+// parseLevels - Will split an alert value into one value per severity, the value is written like
+// "warning:80;clear=75|critical:95". A value without a severity is critical, the values are
+// only split where a severity follows so "|" can still be used inside of the values.
+func parseLevels(value string) map[Severity]string {
+	matches := severityRe.FindAllStringSubmatchIndex(value, -1)
+	if len(matches) == 0 || matches[0][0] != 0 {
+		return map[Severity]string{SeverityCritical: value}
+	}
+
+	levels := map[Severity]string{}
+	for i, m := range matches {
+		end := len(value)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		severity, _ := ParseSeverity(value[m[2]:m[3]])
+		levels[severity] = strings.TrimSpace(value[m[1]:end])
+	}
+	return levels
+}
+
+var msisdnRe = regexp.MustCompile(`^\+?[0-9]+(/\+?[0-9]+)*$`)
+
+// smsService - Will create the SMS service for the settings after "sms:", the settings are either the
+// name of a SMS profile in the config or phone numbers separated by "/" that replace the default recipients
+func smsService(settings string) (services.Service, error) {
+	if !msisdnRe.MatchString(settings) {
+		config, err := services.LoadSMSConfig(settings)
+		if err != nil {
+			return nil, err
+		}
+		return services.NewServiceSMS(config), nil
+	}
+
+	config, _ := services.LoadSMSConfig("")
+	config.Recipients = nil
+	for _, r := range strings.Split(settings, "/") {
+		config.Recipients = append(config.Recipients, strings.TrimPrefix(r, "+"))
+	}
+	if config.Token == "" {
+		return nil, errors.New("sms config needs a token")
+	}
+	return services.NewServiceSMS(config), nil
+}
+
+// NewService - Will create a service from its name like "email" or "sms:oncall", the name
+// can contain settings for the service after the first ":"
+func NewService(name string) (services.Service, error) {
+	var settings string
+	if i := strings.Index(name, ":"); i >= 0 {
+		name, settings = name[:i], strings.TrimSpace(name[i+1:])
+	}
+
+	switch strings.TrimSpace(name) {
+	case "sms":
+		return smsService(settings)
+	case "email":
+		config, err := services.LoadEmailConfig()
+		if err != nil {
+			return nil, err
+		}
+		return services.NewServiceEmail(config), nil
+	case "webhook":
+		config, err := services.LoadWebhookConfig()
+		if err != nil {
+			return nil, err
+		}
+		return services.NewServiceWebhook(config), nil
+	case "chat":
+		config, err := services.LoadChatConfig()
+		if err != nil {
+			return nil, err
+		}
+		return services.NewServiceChat(config), nil
+	}
+	return nil, fmt.Errorf("unknown service: %s", name)
+}
+
+// parseServices - Will parse a comma separated list of services like "email,critical:sms:oncall;contacts=ops,dba",
+// a service with a severity is only notified about alerts with that severity or higher. The contact groups
+// are returned separately, the services send to their contacts instead of the configured recipients
+func parseServices(value string) ([]AlertService, []string) {
+	value, options := providers.ParseOptions(value)
+
+	var groups []string
+	if contactGroups, ok := options["contacts"]; ok {
+		for _, g := range strings.Split(contactGroups, ",") {
+			if g = strings.TrimSpace(g); g != "" {
+				groups = append(groups, g)
+			}
+		}
+	}
+
+	var s []AlertService
+	for _, name := range strings.Split(value, ",") {
+		severity := SeverityInfo
+		if i := strings.Index(name, ":"); i >= 0 {
+			if sev, ok := ParseSeverity(name[:i]); ok {
+				severity = sev
+				name = name[i+1:]
+			}
+		}
+
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		service, err := NewService(name)
+		if err != nil {
+			log.WithError(err).WithField("service", name).Error("error creating service")
+			continue
+		}
+		s = append(s, AlertService{Severity: severity, Name: name, Service: service})
+	}
+	return s, groups
+}
+
+// sortLevels - Will order the levels from the lowest to the highest severity
+func sortLevels(levels []Level) {
+	sort.Slice(levels, func(i, j int) bool {
+		return levels[i].Severity < levels[j].Severity
+	})
+}
+
+// evaluateLevels - Will check every level and return the most severe firing level. When the check failed or
+// the reply has no data the levels that are checked against the reply keep the previous severity, so a single
+// failed check doesn't resolve the alert. Deadman and composite alerts don't depend on the reply
+func evaluateLevels(levels []Level, resp string, replied bool, previous Severity) (Severity, providers.AlertProvider) {
+	// Every level has to be checked as the providers keeps their own history,
+	// the levels are ordered so the last firing level is the most severe
+	severity, al := SeverityNone, levels[0].Provider
+	for _, l := range levels {
+		switch l.Provider.(type) {
+		case *providers.Deadman, *providers.Composite:
+		default:
+			if !replied {
+				if l.Severity == previous {
+					severity, al = l.Severity, l.Provider
+				}
+				continue
+			}
+		}
+		if l.Provider.Check(resp) {
+			severity, al = l.Severity, l.Provider
+		}
+	}
+	return severity, al
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/keiwi/utils/models"
+)
+
+func TestParseLevels(t *testing.T) {
+	tests := []struct {
+		value  string
+		levels map[Severity]string
+	}{
+		{"90", map[Severity]string{SeverityCritical: "90"}},
+		{"", map[Severity]string{SeverityCritical: ""}},
+		{"critical:95", map[Severity]string{SeverityCritical: "95"}},
+		{"warning:80;clear=75|critical:95", map[Severity]string{SeverityWarning: "80;clear=75", SeverityCritical: "95"}},
+		{"info:50 | warning:80 | critical:95", map[Severity]string{SeverityInfo: "50", SeverityWarning: "80", SeverityCritical: "95"}},
+		// The values are only split where a severity follows
+		{"warning:status regex ^(down|failed)$|critical:status == dead", map[Severity]string{
+			SeverityWarning:  "status regex ^(down|failed)$",
+			SeverityCritical: "status == dead",
+		}},
+		// A value that doesn't start with a severity is critical as a whole
+		{"status regex ^(up|warning:x)$", map[Severity]string{SeverityCritical: "status regex ^(up|warning:x)$"}},
+		{"/var:90,/:5GB", map[Severity]string{SeverityCritical: "/var:90,/:5GB"}},
+		{"warning:/var:90,/:5GB|critical:/var:95", map[Severity]string{SeverityWarning: "/var:90,/:5GB", SeverityCritical: "/var:95"}},
+	}
+
+	for _, test := range tests {
+		if levels := parseLevels(test.value); !reflect.DeepEqual(levels, test.levels) {
+			t.Errorf("%q: got %v, expected %v", test.value, levels, test.levels)
+		}
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	for _, s := range []Severity{SeverityInfo, SeverityWarning, SeverityCritical} {
+		if parsed, ok := ParseSeverity(s.String()); !ok || parsed != s {
+			t.Errorf("%s: parsed as %s", s, parsed)
+		}
+	}
+	if _, ok := ParseSeverity("fatal"); ok {
+		t.Error("fatal shouldn't be a severity")
+	}
+}
+
+func TestEvaluateLevels(t *testing.T) {
+	a, err := NewAlert(models.AlertOption{Alert: "cpu", Count: 1, Value: "warning:80;recover=2|critical:95"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		resp     string
+		replied  bool
+		severity Severity
+	}{
+		{"warning", `{"procent":85}`, true, SeverityWarning},
+		{"critical", `{"procent":97}`, true, SeverityCritical},
+		{"failed check", `{"error":"timeout"}`, false, SeverityCritical},
+		{"reply without data", `connection refused`, false, SeverityCritical},
+		{"first recovery", `{"procent":50}`, true, SeverityWarning},
+		// The failed check neither resolves the alert nor resets the recover count
+		{"failed check while recovering", `{"error":"timeout"}`, false, SeverityWarning},
+		{"second recovery", `{"procent":50}`, true, SeverityNone},
+		{"failed check while resolved", `{"error":"timeout"}`, false, SeverityNone},
+	}
+
+	previous := SeverityNone
+	for _, test := range tests {
+		severity, _ := evaluateLevels(a.Levels(), test.resp, test.replied, previous)
+		if severity != test.severity {
+			t.Errorf("%s: severity is %s, expected %s", test.name, severity, test.severity)
+		}
+		previous = severity
+	}
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"sync"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// AcknowledgementRecord - is the acknowledgement of a firing alert, the alert isn't notified
+// about again until it resolves
+type AcknowledgementRecord struct {
+	ID        bson.ObjectId `json:"_id" bson:"_id,omitempty"`
+	AlertID   bson.ObjectId `json:"alert_id" bson:"alert_id"`
+	By        string        `json:"by" bson:"by"`
+	Comment   string        `json:"comment" bson:"comment"`
+	CreatedAt time.Time     `json:"created_at" bson:"created_at"`
+}
+
+// SilenceRecord - is a time bounded silence, no notifications are sent for alerts that it matches.
+// Every ID that is set has to match, a silence without any IDs matches all alerts
+type SilenceRecord struct {
+	ID        bson.ObjectId `json:"_id" bson:"_id,omitempty"`
+	ClientID  bson.ObjectId `json:"client_id,omitempty" bson:"client_id,omitempty"`
+	GroupID   bson.ObjectId `json:"group_id,omitempty" bson:"group_id,omitempty"`
+	CommandID bson.ObjectId `json:"command_id,omitempty" bson:"command_id,omitempty"`
+	AlertID   bson.ObjectId `json:"alert_id,omitempty" bson:"alert_id,omitempty"`
+	Starts    time.Time     `json:"starts" bson:"starts"`
+	Ends      time.Time     `json:"ends" bson:"ends"`
+	By        string        `json:"by" bson:"by"`
+	Comment   string        `json:"comment" bson:"comment"`
+	CreatedAt time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+// Active - Will return whether the silence is active at the specific time
+func (s SilenceRecord) Active(t time.Time) bool {
+	return !t.Before(s.Starts) && t.Before(s.Ends)
+}
+
+// Matches - Will return whether the silence matches the alert on the check of the client
+func (s SilenceRecord) Matches(client *Client, check *Check, alert *Alert) bool {
+	if s.ClientID != "" && s.ClientID != client.ID() {
+		return false
+	}
+	if s.GroupID != "" && (check.Group() == nil || s.GroupID != check.Group().ID()) {
+		return false
+	}
+	if s.CommandID != "" && s.CommandID != check.Command().ID() {
+		return false
+	}
+	if s.AlertID != "" && s.AlertID != alert.ID() {
+		return false
+	}
+	return true
+}
+
+// NewSilenceList - Will create an empty list of silences
+func NewSilenceList() *SilenceList {
+	return &SilenceList{
+		rw:       new(sync.RWMutex),
+		silences: map[bson.ObjectId]SilenceRecord{},
+	}
+}
+
+// silences - are the silences that all alerts are checked against before they notify
+var silences = NewSilenceList()
+
+// Silences - Will return the silences that are used by the alerts
+func Silences() *SilenceList {
+	return silences
+}
+
+// SilenceList - contains all of the silences that haven't ended
+type SilenceList struct {
+	rw       *sync.RWMutex
+	silences map[bson.ObjectId]SilenceRecord
+}
+
+// SetSilences - Will replace all of the silences
+func (s *SilenceList) SetSilences(silences []SilenceRecord) {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+	s.silences = map[bson.ObjectId]SilenceRecord{}
+	for _, silence := range silences {
+		s.silences[silence.ID] = silence
+	}
+}
+
+// UpdateSilence - Will add the silence or modify it if it already exists
+func (s *SilenceList) UpdateSilence(silence SilenceRecord) {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+	s.silences[silence.ID] = silence
+}
+
+// RemoveSilenceByID - Will remove a silence before it has ended
+func (s *SilenceList) RemoveSilenceByID(id bson.ObjectId) {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+	delete(s.silences, id)
+}
+
+// Silenced - Will return whether any active silence matches the alert on the check of the client,
+// silences that have ended are removed
+func (s *SilenceList) Silenced(client *Client, check *Check, alert *Alert) bool {
+	now := time.Now()
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	silenced := false
+	for id, silence := range s.silences {
+		if !now.Before(silence.Ends) {
+			delete(s.silences, id)
+			continue
+		}
+		if silence.Active(now) && silence.Matches(client, check, alert) {
+			silenced = true
+		}
+	}
+	return silenced
+}
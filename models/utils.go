@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/keiwi/utils"
+	"github.com/keiwi/utils/log"
 	"github.com/keiwi/utils/models"
 	"github.com/nats-io/go-nats"
 	"github.com/pkg/errors"
@@ -75,6 +76,70 @@ func FindAllCommands(conn *nats.Conn) ([]models.Command, error) {
 	return commands, nil
 }
 
+func FindAllContacts(conn *nats.Conn) ([]ContactRecord, error) {
+	requestData := utils.FindOptions{
+		Sort: utils.Sort{"created_at"},
+	}
+	data, err := bson.MarshalJSON(requestData)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := conn.Request("contacts.retrieve.find", data, time.Duration(viper.GetInt("nats_delay"))*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	var contacts []ContactRecord
+	err = bson.UnmarshalJSON(msg.Data, &contacts)
+	if err != nil {
+		return nil, err
+	}
+	return contacts, nil
+}
+
+func FindAllContactGroups(conn *nats.Conn) ([]ContactGroupRecord, error) {
+	requestData := utils.FindOptions{
+		Sort: utils.Sort{"created_at"},
+	}
+	data, err := bson.MarshalJSON(requestData)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := conn.Request("contact_groups.retrieve.find", data, time.Duration(viper.GetInt("nats_delay"))*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []ContactGroupRecord
+	err = bson.UnmarshalJSON(msg.Data, &groups)
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func FindActiveSilences(conn *nats.Conn) ([]SilenceRecord, error) {
+	requestData := utils.FindOptions{
+		Filter: utils.Filter{"ends": bson.M{"$gt": time.Now()}},
+		Sort:   utils.Sort{"created_at"},
+	}
+	data, err := bson.MarshalJSON(requestData)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := conn.Request("silences.retrieve.find", data, time.Duration(viper.GetInt("nats_delay"))*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	var silences []SilenceRecord
+	err = bson.UnmarshalJSON(msg.Data, &silences)
+	if err != nil {
+		return nil, err
+	}
+	return silences, nil
+}
+
 func FindCheck(conn *nats.Conn, filter utils.Filter) ([]models.Check, error) {
 	requestData := utils.FindOptions{
 		Filter: filter,
@@ -168,6 +233,23 @@ func NewManager(conn *nats.Conn) (*Manager, error) {
 		return clients, errors.Wrap(err, "error finding all commands")
 	}
 
+	// Contacts are optional, without them the services use their configured recipients
+	contactList, err := FindAllContacts(conn)
+	if err != nil {
+		log.WithError(err).Warn("error finding all contacts")
+	}
+	contactGroups, err := FindAllContactGroups(conn)
+	if err != nil {
+		log.WithError(err).Warn("error finding all contact groups")
+	}
+	Contacts().SetContacts(contactList, contactGroups)
+
+	activeSilences, err := FindActiveSilences(conn)
+	if err != nil {
+		log.WithError(err).Warn("error finding active silences")
+	}
+	Silences().SetSilences(activeSilences)
+
 	// Create virtual commands groups
 	c := ConvertCommands(cmds)
 	g := ConvertGroups(groups, c)
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+
+	"github.com/keiwi/server/services"
+	"github.com/keiwi/utils/log"
+	"github.com/nats-io/go-nats"
+)
+
+// notificationRequest is the request to retry or delete a dead notification
+type notificationRequest struct {
+	ID string `json:"id"`
+}
+
+// notificationResponse is the reply to the notification requests
+type notificationResponse struct {
+	Jobs  []services.Job `json:"jobs,omitempty"`
+	Error string         `json:"error,omitempty"`
+}
+
+// publishNotificationStatus publishes the delivery status of a notification for a service
+func publishNotificationStatus(job services.Job) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		log.WithError(err).Error("error marshaling notification status")
+		return
+	}
+	if err = natsConn.Publish("notifications.status.send", data); err != nil {
+		log.WithError(err).WithField("job_id", job.ID).Error("error publishing notification status")
+	}
+}
+
+// handleNotifications makes the notification queue and its dead letters available over nats
+func handleNotifications() {
+	natsConn.Subscribe("notifications.queue.find", func(m *nats.Msg) {
+		respond(m, notificationResponse{Jobs: dispatcher.Jobs()})
+	})
+
+	natsConn.Subscribe("notifications.deadletters.find", func(m *nats.Msg) {
+		jobs, err := dispatcher.DeadLetters()
+		if err != nil {
+			respond(m, notificationResponse{Error: err.Error()})
+			return
+		}
+		respond(m, notificationResponse{Jobs: jobs})
+	})
+
+	natsConn.Subscribe("notifications.deadletters.retry", func(m *nats.Msg) {
+		var req notificationRequest
+		if err := json.Unmarshal(m.Data, &req); err != nil {
+			respond(m, notificationResponse{Error: err.Error()})
+			return
+		}
+		if err := dispatcher.RetryDeadLetter(req.ID); err != nil {
+			respond(m, notificationResponse{Error: err.Error()})
+			return
+		}
+		respond(m, notificationResponse{})
+	})
+
+	natsConn.Subscribe("notifications.deadletters.delete", func(m *nats.Msg) {
+		var req notificationRequest
+		if err := json.Unmarshal(m.Data, &req); err != nil {
+			respond(m, notificationResponse{Error: err.Error()})
+			return
+		}
+		if err := dispatcher.DeleteDeadLetter(req.ID); err != nil {
+			respond(m, notificationResponse{Error: err.Error()})
+			return
+		}
+		respond(m, notificationResponse{})
+	})
+}
+
+// respond replies to a nats request, messages without a reply subject are ignored
+func respond(m *nats.Msg, resp interface{}) {
+	if m.Reply == "" {
+		return
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		log.WithError(err).Errorf("error marshaling response (%s)", m.Subject)
+		return
+	}
+	if err = natsConn.Publish(m.Reply, data); err != nil {
+		log.WithError(err).Errorf("error responding (%s)", m.Subject)
+	}
+}
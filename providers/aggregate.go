@@ -0,0 +1,98 @@
+package providers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Aggregation is how the values in a window are combined before they are compared with the threshold
+type Aggregation string
+
+// Supported aggregations, percentiles can be any "p" followed by a number between 0 and 100 like p50, p95 or p99
+const (
+	AggregationAvg  Aggregation = "avg"
+	AggregationMin  Aggregation = "min"
+	AggregationMax  Aggregation = "max"
+	AggregationLast Aggregation = "last"
+	AggregationSum  Aggregation = "sum"
+)
+
+// ParseAggregation reads the "agg" option from a value like "85;agg=p95", it defaults to avg
+func ParseAggregation(value string) (Aggregation, error) {
+	_, options := ParseOptions(value)
+	agg, ok := options["agg"]
+	if !ok || agg == "" {
+		return AggregationAvg, nil
+	}
+
+	g := Aggregation(strings.ToLower(agg))
+	switch g {
+	case AggregationAvg, AggregationMin, AggregationMax, AggregationLast, AggregationSum:
+		return g, nil
+	}
+	if _, ok := g.percentile(); ok {
+		return g, nil
+	}
+	return "", fmt.Errorf("the aggregation: %s is not one of avg, min, max, last, sum or a percentile like p95", agg)
+}
+
+// percentile returns the percentile if the aggregation is one
+func (g Aggregation) percentile() (float64, bool) {
+	if !strings.HasPrefix(string(g), "p") {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(string(g[1:]), 64)
+	if err != nil || math.IsNaN(p) || math.IsInf(p, 0) || p < 0 || p > 100 {
+		return 0, false
+	}
+	return p, true
+}
+
+// apply combines the values, the values are not modified
+func (g Aggregation) apply(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	switch g {
+	case AggregationMin:
+		min := values[0]
+		for _, v := range values[1:] {
+			min = math.Min(min, v)
+		}
+		return min
+	case AggregationMax:
+		max := values[0]
+		for _, v := range values[1:] {
+			max = math.Max(max, v)
+		}
+		return max
+	case AggregationLast:
+		return values[len(values)-1]
+	case AggregationSum, AggregationAvg, "":
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		if g == AggregationSum {
+			return sum
+		}
+		return sum / float64(len(values))
+	}
+
+	p, ok := g.percentile()
+	if !ok {
+		return 0
+	}
+
+	// Linear interpolation between the closest ranks
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
@@ -0,0 +1,81 @@
+package providers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseAggregation(t *testing.T) {
+	tests := []struct {
+		value string
+		agg   Aggregation
+		err   bool
+	}{
+		{value: "80", agg: AggregationAvg},
+		{value: "80;agg=", agg: AggregationAvg},
+		{value: "80;agg=MAX", agg: AggregationMax},
+		{value: "80;agg=p95", agg: "p95"},
+		{value: "80;agg=p99.9", agg: "p99.9"},
+		{value: "80;agg=p0", agg: "p0"},
+		{value: "80;agg=p100", agg: "p100"},
+		{value: "80;agg=median", err: true},
+		{value: "80;agg=p", err: true},
+		{value: "80;agg=p101", err: true},
+		{value: "80;agg=p-1", err: true},
+		{value: "80;agg=pnan", err: true},
+		{value: "80;agg=pNaN", err: true},
+		{value: "80;agg=pinf", err: true},
+		{value: "80;agg=p-inf", err: true},
+	}
+
+	for _, test := range tests {
+		agg, err := ParseAggregation(test.value)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %q", test.value, agg)
+			}
+			continue
+		}
+		if err != nil || agg != test.agg {
+			t.Errorf("%q: got %q %v, expected %q", test.value, agg, err, test.agg)
+		}
+	}
+}
+
+func TestAggregationApply(t *testing.T) {
+	values := []float64{30, 10, 50, 20, 40}
+
+	tests := []struct {
+		agg    Aggregation
+		values []float64
+		result float64
+	}{
+		{AggregationAvg, values, 30},
+		{AggregationMin, values, 10},
+		{AggregationMax, values, 50},
+		{AggregationLast, values, 40},
+		{AggregationSum, values, 150},
+		{"p0", values, 10},
+		{"p100", values, 50},
+		{"p50", values, 30},
+		// Interpolated between the closest ranks
+		{"p90", values, 46},
+		{"p95", values, 48},
+		{"p10", values, 14},
+		{"p50", []float64{10, 20}, 15},
+		{"p99", []float64{42}, 42},
+		{"p95", nil, 0},
+		{"pnan", values, 0},
+	}
+
+	for _, test := range tests {
+		if result := test.agg.apply(test.values); math.Abs(result-test.result) > 1e-9 {
+			t.Errorf("%s of %v: got %v, expected %v", test.agg, test.values, result, test.result)
+		}
+	}
+
+	// The window isn't sorted in place
+	if values[0] != 30 || values[4] != 40 {
+		t.Errorf("the values were modified: %v", values)
+	}
+}
@@ -0,0 +1,181 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Baseline is an exponentially weighted mean and variance of the values seen so far
+type Baseline struct {
+	Mean     float64
+	Variance float64
+	Count    int
+}
+
+func (b *Baseline) add(value, alpha float64) {
+	b.Count++
+	if b.Count == 1 {
+		b.Mean = value
+		return
+	}
+	diff := value - b.Mean
+	incr := alpha * diff
+	b.Mean += incr
+	b.Variance = (1 - alpha) * (b.Variance + diff*incr)
+}
+
+func (b Baseline) stddev() float64 {
+	return math.Sqrt(b.Variance)
+}
+
+// Anomaly learns a baseline for a value in the check response and fires when a sample
+// deviates more then Sigma standard deviations from it. The option is written like
+// "load.avg1;sigma=3;alpha=0.05;warmup=50;seasonal" where seasonal keeps a separate
+// baseline for every hour of the week
+type Anomaly struct {
+	rw        *sync.RWMutex
+	Path      []string
+	Sigma     float64
+	Alpha     float64
+	Warmup    int
+	Seasonal  bool
+	Baselines map[int]*Baseline
+	Current   float64
+	Expected  float64
+	Deviation float64
+}
+
+// ParseAnomaly parses an anomaly option, sigma defaults to 3, alpha to 0.1 and warmup to 30 samples
+func ParseAnomaly(value string) (path []string, sigma, alpha float64, warmup int, seasonal bool, err error) {
+	p, options := ParseOptions(value)
+	if p == "" {
+		return nil, 0, 0, 0, false, fmt.Errorf("no json path was provided")
+	}
+	path = strings.Split(p, ".")
+	sigma, alpha, warmup = 3, 0.1, 30
+
+	if s, ok := options["sigma"]; ok {
+		if sigma, err = strconv.ParseFloat(s, 64); err != nil || sigma <= 0 {
+			return nil, 0, 0, 0, false, fmt.Errorf("the sigma: %s has to be a positive number", s)
+		}
+	}
+	if a, ok := options["alpha"]; ok {
+		if alpha, err = strconv.ParseFloat(a, 64); err != nil || alpha <= 0 || alpha > 1 {
+			return nil, 0, 0, 0, false, fmt.Errorf("the alpha: %s has to be a number between 0 and 1", a)
+		}
+	}
+	if w, ok := options["warmup"]; ok {
+		if warmup, err = strconv.Atoi(w); err != nil || warmup < 0 {
+			return nil, 0, 0, 0, false, fmt.Errorf("the warmup: %s has to be a positive integer", w)
+		}
+	}
+	_, seasonal = options["seasonal"]
+	return path, sigma, alpha, warmup, seasonal, nil
+}
+
+func (a Anomaly) Name() string {
+	return "Anomaly"
+}
+
+func (a *Anomaly) Check(resp string) bool {
+	f, ok := a.sample(resp)
+	if !ok {
+		return false
+	}
+
+	a.rw.Lock()
+	defer a.rw.Unlock()
+	b := a.baseline(time.Now())
+
+	a.Current = f
+	a.Expected = b.Mean
+	a.Deviation = 0
+	anomaly := false
+	if b.Count >= a.Warmup && b.stddev() > 0 {
+		a.Deviation = math.Abs(f-b.Mean) / b.stddev()
+		anomaly = a.Deviation > a.Sigma
+	}
+
+	b.add(f, a.Alpha)
+	return anomaly
+}
+
+// Learn adds the value of a stored response to the baseline for when it was received without
+// checking it, it's used to learn the baseline from the check history
+func (a *Anomaly) Learn(resp string, at time.Time) {
+	f, ok := a.sample(resp)
+	if !ok {
+		return
+	}
+
+	a.rw.Lock()
+	defer a.rw.Unlock()
+	a.baseline(at).add(f, a.Alpha)
+}
+
+// sample returns the value at the path in the response
+func (a Anomaly) sample(resp string) (float64, bool) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(resp), &data); err != nil {
+		return 0, false
+	}
+
+	a.rw.RLock()
+	value, ok := lookupJSON(data, a.Path)
+	a.rw.RUnlock()
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(jsonString(value), 64)
+	return f, err == nil
+}
+
+// baseline returns the baseline for the time, it's created if it doesn't exist. The lock has to be held
+func (a *Anomaly) baseline(t time.Time) *Baseline {
+	bucket := 0
+	if a.Seasonal {
+		bucket = int(t.Weekday())*24 + t.Hour()
+	}
+	b, ok := a.Baselines[bucket]
+	if !ok {
+		b = &Baseline{}
+		a.Baselines[bucket] = b
+	}
+	return b
+}
+
+// Samples returns the amount of values the baselines have learned from
+func (a Anomaly) Samples() int {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	var count int
+	for _, b := range a.Baselines {
+		count += b.Count
+	}
+	return count
+}
+
+func (a Anomaly) Value() string {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return strconv.FormatFloat(a.Current, 'g', -1, 64)
+}
+
+// Threshold returns the amount of standard deviations from the baseline the alert fires at
+func (a Anomaly) Threshold() string {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return strconv.FormatFloat(a.Sigma, 'f', -1, 64) + " sigma"
+}
+
+func (a Anomaly) Message() string {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return fmt.Sprintf("%s: %s is %.1f sigma from the expected %.2f", strings.Join(a.Path, "."),
+		strconv.FormatFloat(a.Current, 'g', -1, 64), a.Deviation, a.Expected)
+}
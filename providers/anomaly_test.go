@@ -0,0 +1,91 @@
+package providers
+
+import (
+	"testing"
+	"time"
+)
+
+// learned creates an anomaly provider that has learned a baseline alternating between 10 and 12, the mean
+// of the baseline is about 10.9 and the standard deviation about 1
+func learned(t *testing.T, value string, samples int) *Anomaly {
+	p, err := NewAlertProviderAnomaly(value)
+	if err != nil {
+		t.Fatalf("%q: %v", value, err)
+	}
+	a := p.(*Anomaly)
+	for i := 0; i < samples; i++ {
+		v := "10"
+		if i%2 == 1 {
+			v = "12"
+		}
+		a.Learn(`{"load":{"avg1":`+v+`}}`, time.Now())
+	}
+	return a
+}
+
+func TestAnomalyCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		samples int
+		resp    string
+		firing  bool
+	}{
+		{"within sigma", "load.avg1;warmup=20", 20, `{"load":{"avg1":13}}`, false},
+		{"above sigma", "load.avg1;warmup=20", 20, `{"load":{"avg1":14.5}}`, true},
+		{"below sigma", "load.avg1;warmup=20", 20, `{"load":{"avg1":7}}`, true},
+		{"within a larger sigma", "load.avg1;warmup=20;sigma=4", 20, `{"load":{"avg1":14.5}}`, false},
+		{"above a smaller sigma", "load.avg1;warmup=20;sigma=2", 20, `{"load":{"avg1":13}}`, true},
+		{"warming up", "load.avg1;warmup=30", 20, `{"load":{"avg1":100}}`, false},
+		{"warmed up", "load.avg1;warmup=20", 20, `{"load":{"avg1":100}}`, true},
+		{"without deviation", "load.avg1;warmup=0", 0, `{"load":{"avg1":100}}`, false},
+		{"without a value", "load.avg1;warmup=20", 20, `{"load":{}}`, false},
+	}
+
+	for _, test := range tests {
+		a := learned(t, test.value, test.samples)
+		if firing := a.Check(test.resp); firing != test.firing {
+			t.Errorf("%s: firing is %t, expected %t (%s)", test.name, firing, test.firing, a.Message())
+		}
+	}
+}
+
+func TestAnomalyLearnSeasonal(t *testing.T) {
+	p, err := NewAlertProviderAnomaly("v;seasonal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := p.(*Anomaly)
+
+	// 2026-01-05 is a monday
+	a.Learn(`{"v":1}`, time.Date(2026, 1, 5, 12, 30, 0, 0, time.UTC))
+	a.Learn(`{"v":2}`, time.Date(2026, 1, 5, 12, 45, 0, 0, time.UTC))
+	a.Learn(`{"v":3}`, time.Date(2026, 1, 6, 12, 0, 0, 0, time.UTC))
+	a.Learn(`{"v":"x"}`, time.Date(2026, 1, 6, 12, 0, 0, 0, time.UTC))
+
+	if b := a.Baselines[1*24+12]; b == nil || b.Count != 2 {
+		t.Errorf("monday noon baseline is %+v, expected 2 samples", b)
+	}
+	if b := a.Baselines[2*24+12]; b == nil || b.Count != 1 {
+		t.Errorf("tuesday noon baseline is %+v, expected 1 sample", b)
+	}
+	if n := a.Samples(); n != 3 {
+		t.Errorf("learned from %d samples, expected 3", n)
+	}
+}
+
+func TestParseAnomalyErrors(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"v;sigma=0",
+		"v;sigma=high",
+		"v;alpha=0",
+		"v;alpha=1.5",
+		"v;warmup=-1",
+		"v;warmup=some",
+	} {
+		if _, err := NewAlertProviderAnomaly(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Expression is a boolean expression of alert and check states
+type Expression interface {
+	Evaluate(states map[string]bool) bool
+	String() string
+}
+
+type exprRef string
+
+func (e exprRef) Evaluate(states map[string]bool) bool { return states[string(e)] }
+func (e exprRef) String() string                       { return string(e) }
+
+type exprNot struct{ expr Expression }
+
+func (e exprNot) Evaluate(states map[string]bool) bool { return !e.expr.Evaluate(states) }
+func (e exprNot) String() string                       { return "NOT " + e.expr.String() }
+
+type exprBinary struct {
+	op          string
+	left, right Expression
+}
+
+func (e exprBinary) Evaluate(states map[string]bool) bool {
+	if e.op == "AND" {
+		return e.left.Evaluate(states) && e.right.Evaluate(states)
+	}
+	return e.left.Evaluate(states) || e.right.Evaluate(states)
+}
+
+func (e exprBinary) String() string {
+	return fmt.Sprintf("(%s %s %s)", e.left, e.op, e.right)
+}
+
+// ParseExpression parses an expression like "alert:<id> AND (check:<command id> OR NOT alert:<id>)",
+// alert references are true when the alert is firing and check references when the check has an error.
+// The operators can also be written as &&, || and !
+func ParseExpression(expr string) (Expression, error) {
+	p := &exprParser{tokens: tokenize(expr)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("the expression is empty")
+	}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s in the expression", p.tokens[p.pos])
+	}
+	return e, nil
+}
+
+func tokenize(expr string) []string {
+	r := strings.NewReplacer("(", " ( ", ")", " ) ", "&&", " AND ", "||", " OR ", "!", " NOT ")
+	var tokens []string
+	for _, t := range strings.Fields(r.Replace(expr)) {
+		switch u := strings.ToUpper(t); u {
+		case "AND", "OR", "NOT":
+			t = u
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
+
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *exprParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) or() (Expression, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "OR" {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = exprBinary{op: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) and() (Expression, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "AND" {
+		p.pos++
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = exprBinary{op: "AND", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) not() (Expression, error) {
+	if p.peek() == "NOT" {
+		p.pos++
+		e, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return exprNot{expr: e}, nil
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (Expression, error) {
+	t := p.peek()
+	p.pos++
+	switch {
+	case t == "":
+		return nil, fmt.Errorf("unexpected end of the expression")
+	case t == "(":
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis in the expression")
+		}
+		p.pos++
+		return e, nil
+	case strings.HasPrefix(t, "alert:") || strings.HasPrefix(t, "check:"):
+		return exprRef(t), nil
+	}
+	return nil, fmt.Errorf("unexpected %s in the expression, references are written as alert:<id> or check:<command id>", t)
+}
+
+// Composite fires when the expression of other alerts and checks on the same client is true,
+// it doesn't look at the response itself but relies on Update being called with the client state
+type Composite struct {
+	rw         *sync.RWMutex
+	Expression Expression
+	States     map[string]bool
+}
+
+func (a Composite) Name() string {
+	return "Composite"
+}
+
+// Update modifies the alert and check states that the expression is evaluated against
+func (a *Composite) Update(states map[string]bool) {
+	a.rw.Lock()
+	defer a.rw.Unlock()
+	a.States = states
+}
+
+func (a *Composite) Check(resp string) bool {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return a.Expression.Evaluate(a.States)
+}
+
+func (a Composite) Value() string {
+	return strconv.FormatBool(a.Check(""))
+}
+
+func (a Composite) Message() string {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+
+	seen := map[string]bool{}
+	var refs []string
+	for _, t := range tokenize(a.Expression.String()) {
+		if strings.Contains(t, ":") && !seen[t] {
+			seen[t] = true
+			refs = append(refs, fmt.Sprintf("%s=%t", t, a.States[t]))
+		}
+	}
+	sort.Strings(refs)
+	return fmt.Sprintf("Composite: %s [%s]", a.Expression, strings.Join(refs, ", "))
+}
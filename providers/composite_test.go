@@ -0,0 +1,76 @@
+package providers
+
+import "testing"
+
+func TestParseExpression(t *testing.T) {
+	tests := []struct {
+		expr   string
+		parsed string
+	}{
+		{"alert:a", "alert:a"},
+		{"alert:a AND check:b", "(alert:a AND check:b)"},
+		{"alert:a and check:b or alert:c", "((alert:a AND check:b) OR alert:c)"},
+		// AND binds harder then OR
+		{"alert:a OR check:b AND alert:c", "(alert:a OR (check:b AND alert:c))"},
+		{"(alert:a OR check:b) AND alert:c", "((alert:a OR check:b) AND alert:c)"},
+		{"NOT alert:a AND check:b", "(NOT alert:a AND check:b)"},
+		{"not not alert:a", "NOT NOT alert:a"},
+		{"alert:a&&!(check:b||check:c)", "(alert:a AND NOT (check:b OR check:c))"},
+	}
+
+	for _, test := range tests {
+		e, err := ParseExpression(test.expr)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.expr, err)
+			continue
+		}
+		if parsed := e.String(); parsed != test.parsed {
+			t.Errorf("%q: parsed as %q, expected %q", test.expr, parsed, test.parsed)
+		}
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"   ",
+		"alert:a AND",
+		"OR alert:a",
+		"NOT",
+		"(alert:a OR check:b",
+		"alert:a)",
+		"alert:a check:b",
+		"client:a",
+		"()",
+	} {
+		if _, err := ParseExpression(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
+
+func TestCompositeCheck(t *testing.T) {
+	p, err := NewAlertProviderComposite("alert:cpu AND (check:ping OR NOT alert:memory)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := p.(*Composite)
+
+	tests := []struct {
+		states map[string]bool
+		firing bool
+	}{
+		{map[string]bool{}, false},
+		{map[string]bool{"alert:cpu": true}, true},
+		{map[string]bool{"alert:cpu": true, "alert:memory": true}, false},
+		{map[string]bool{"alert:cpu": true, "alert:memory": true, "check:ping": true}, true},
+		{map[string]bool{"alert:memory": false, "check:ping": true}, false},
+	}
+
+	for _, test := range tests {
+		c.Update(test.states)
+		if firing := c.Check(""); firing != test.firing {
+			t.Errorf("%v: firing is %t, expected %t", test.states, firing, test.firing)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package providers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ParseTimeout parses a timeout either as a duration like "10m" or as seconds
+func ParseTimeout(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	if s, err := strconv.Atoi(value); err == nil {
+		return time.Duration(s) * time.Second, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("the value: %s is not a valid duration", value)
+	}
+	return d, nil
+}
+
+// Deadman fires when a check hasn't produced a successful response within the timeout or when the
+// client has been without a connection for longer than one check interval, it doesn't look at the
+// response itself but relies on Update being called with the check state
+type Deadman struct {
+	rw           *sync.RWMutex
+	Timeout      time.Duration // Time without a successful check, if zero Total missed checks is used instead
+	Total        int
+	Created      time.Time
+	LastSuccess  time.Time
+	Interval     time.Duration
+	Connected    bool
+	Disconnected time.Time // When the client lost its connection, zero while it's connected
+}
+
+func (a Deadman) Name() string {
+	return "Deadman"
+}
+
+// Update modifies the check state that the deadman is based on
+func (a *Deadman) Update(lastSuccess time.Time, interval time.Duration, connected bool) {
+	a.rw.Lock()
+	defer a.rw.Unlock()
+	a.LastSuccess = lastSuccess
+	a.Interval = interval
+	if !connected && a.Disconnected.IsZero() {
+		a.Disconnected = time.Now()
+	} else if connected {
+		a.Disconnected = time.Time{}
+	}
+	a.Connected = connected
+}
+
+func (a *Deadman) Check(resp string) bool {
+	timeout := a.timeout()
+	if since, ok := a.disconnected(); ok && since > a.grace(timeout) {
+		return true
+	}
+	return a.elapsed() > timeout
+}
+
+// grace returns how long a client can be without a connection before the alert fires, the client
+// gets one check interval to connect again but never longer than the timeout
+func (a Deadman) grace(timeout time.Duration) time.Duration {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	if a.Interval <= 0 || a.Interval > timeout {
+		return timeout
+	}
+	return a.Interval
+}
+
+// disconnected returns the time since the client lost its connection, false is returned while it's connected
+func (a Deadman) disconnected() (time.Duration, bool) {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	if a.Connected || a.Disconnected.IsZero() {
+		return 0, false
+	}
+	return time.Since(a.Disconnected), true
+}
+
+// timeout returns the configured timeout or the time for the amount of missed checks,
+// one extra interval is added to give the last check time to finish
+func (a Deadman) timeout() time.Duration {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	if a.Timeout > 0 {
+		return a.Timeout
+	}
+	missed := a.Total
+	if missed < 1 {
+		missed = 1
+	}
+	return a.Interval * time.Duration(missed+1)
+}
+
+// elapsed returns the time since the last successful check, or since the
+// deadman was created if there never was a successful check
+func (a Deadman) elapsed() time.Duration {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	since := a.LastSuccess
+	if since.Before(a.Created) {
+		since = a.Created
+	}
+	return time.Since(since)
+}
+
+func (a Deadman) GetConnected() bool {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return a.Connected
+}
+
+func (a Deadman) Value() string {
+	return a.elapsed().Truncate(time.Second).String()
+}
+
+// Threshold returns the time without a successful check before the alert fires
+func (a Deadman) Threshold() string {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	if a.Timeout > 0 {
+		return a.Timeout.String()
+	}
+	return fmt.Sprintf("%d missed checks", a.Total)
+}
+
+func (a Deadman) Message() string {
+	if since, ok := a.disconnected(); ok {
+		return fmt.Sprintf("Client has no connection for %s, no successful check for %s (limit %s)",
+			since.Truncate(time.Second), a.Value(), a.timeout())
+	}
+	return fmt.Sprintf("No successful check for %s (limit %s)", a.Value(), a.timeout())
+}
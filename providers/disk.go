@@ -0,0 +1,279 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DiskUsage contains information about the usage of all mountpoints on a client
+type DiskUsage struct {
+	Error string      `json:"error"`
+	Disks []DiskMount `json:"disks"`
+}
+
+// DiskMount contains information about the usage of a single mountpoint
+type DiskMount struct {
+	Mountpoint string  `json:"mountpoint"`
+	Total      uint64  `json:"total"`
+	Used       uint64  `json:"used"`
+	Free       uint64  `json:"free"`
+	Procent    float64 `json:"procent"`
+}
+
+func (d DiskMount) procent() float64 {
+	if d.Procent == 0 && d.Total > 0 {
+		return float64(d.Used) / float64(d.Total) * 100
+	}
+	return d.Procent
+}
+
+// DiskThreshold is the limit for a mountpoint, either a maximum usage in percent
+// or a minimum amount of free bytes
+type DiskThreshold struct {
+	Mountpoint   string
+	Bytes        bool // The threshold is the free space in bytes, the usage in percent otherwise
+	Procent      float64
+	Free         uint64
+	ClearProcent float64 // The usage has to drop to or below this before the mountpoint clears
+	ClearFree    uint64  // The free space has to grow to or above this before the mountpoint clears
+}
+
+// match returns whether the threshold applies to a mountpoint
+func (t DiskThreshold) match(mountpoint string) bool {
+	return t.Mountpoint == "*" || t.Mountpoint == mountpoint
+}
+
+// exceeded returns whether the mountpoint has crossed the threshold
+func (t DiskThreshold) exceeded(d DiskMount) bool {
+	if t.Bytes {
+		return d.Free < t.Free
+	}
+	return d.procent() > t.Procent
+}
+
+// cleared returns whether the mountpoint has reached the clear threshold
+func (t DiskThreshold) cleared(d DiskMount) bool {
+	if t.Bytes {
+		return d.Free >= t.ClearFree
+	}
+	return d.procent() <= t.ClearProcent
+}
+
+func (t DiskThreshold) String() string {
+	if t.Bytes {
+		return formatBytes(t.Free) + " free"
+	}
+	return strconv.FormatFloat(t.Procent, 'g', -1, 64) + "%"
+}
+
+// ParseDiskThresholds parses a threshold string like "/var:90/85,/:5GB/8GB,*:95", a
+// threshold without a mountpoint applies to all mountpoints. Percentages are
+// the maximum usage while sizes are the minimum amount of free space, the
+// optional value after "/" is the clear threshold of the mountpoint.
+func ParseDiskThresholds(value string) ([]DiskThreshold, error) {
+	value, options := ParseOptions(value)
+	if _, ok := options["clear"]; ok {
+		return nil, fmt.Errorf("disk clear thresholds are written after the threshold of each mountpoint like \"/var:90/85\"")
+	}
+
+	var thresholds []DiskThreshold
+	for _, v := range strings.Split(strings.Replace(value, " ", "", -1), ",") {
+		if v == "" {
+			continue
+		}
+
+		t := DiskThreshold{Mountpoint: "*"}
+		limit := v
+		if i := strings.LastIndex(v, ":"); i >= 0 {
+			t.Mountpoint = v[:i]
+			limit = v[i+1:]
+		}
+
+		clear := ""
+		if i := strings.Index(limit, "/"); i >= 0 {
+			limit, clear = limit[:i], limit[i+1:]
+		}
+
+		if procent, err := strconv.ParseFloat(strings.TrimSuffix(limit, "%"), 64); err == nil {
+			if procent <= 0 {
+				return nil, fmt.Errorf("the threshold: %s has to be larger then zero", limit)
+			}
+			t.Procent, t.ClearProcent = procent, procent
+			if clear != "" {
+				if t.ClearProcent, err = strconv.ParseFloat(strings.TrimSuffix(clear, "%"), 64); err != nil {
+					return nil, fmt.Errorf("the clear threshold: %s is not a percentage", clear)
+				}
+				if t.ClearProcent > t.Procent {
+					return nil, fmt.Errorf("the clear threshold: %s is larger then the threshold: %s", clear, limit)
+				}
+			}
+		} else {
+			free, err := parseBytes(limit)
+			if err != nil {
+				return nil, fmt.Errorf("the value: %s is neither a percentage or a size", limit)
+			}
+			if free == 0 {
+				return nil, fmt.Errorf("the threshold: %s has to be larger then zero", limit)
+			}
+			t.Bytes = true
+			t.Free, t.ClearFree = free, free
+			if clear != "" {
+				if t.ClearFree, err = parseBytes(clear); err != nil {
+					return nil, fmt.Errorf("the clear threshold: %s is not a size", clear)
+				}
+				if t.ClearFree < t.Free {
+					return nil, fmt.Errorf("the clear threshold: %s is smaller then the threshold: %s", clear, limit)
+				}
+			}
+		}
+		thresholds = append(thresholds, t)
+	}
+
+	if len(thresholds) == 0 {
+		return nil, fmt.Errorf("no disk thresholds were provided")
+	}
+	return thresholds, nil
+}
+
+// byteUnits is ordered so the longest suffix is tried first
+var byteUnits = []struct {
+	suffix string
+	size   uint64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+func parseBytes(s string) (uint64, error) {
+	s = strings.ToUpper(s)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			v, err := strconv.ParseFloat(strings.TrimSuffix(s, u.suffix), 64)
+			if err != nil {
+				return 0, err
+			}
+			return uint64(v * float64(u.size)), nil
+		}
+	}
+	return 0, fmt.Errorf("the value: %s is missing a size unit", s)
+}
+
+func formatBytes(b uint64) string {
+	for _, u := range byteUnits {
+		if b >= u.size {
+			return strconv.FormatFloat(float64(b)/float64(u.size), 'f', 1, 64) + u.suffix
+		}
+	}
+	return "0B"
+}
+
+type Disk struct {
+	rw         *sync.RWMutex
+	Thresholds []DiskThreshold
+	Exceeded   []DiskMount // The mountpoints that haven't cleared
+	Hysteresis Hysteresis
+}
+
+func (a Disk) Name() string {
+	return "Disk"
+}
+
+func (a *Disk) Check(resp string) bool {
+	usage := DiskUsage{}
+	err := json.Unmarshal([]byte(resp), &usage)
+	if err != nil || usage.Error != "" {
+		return false
+	}
+
+	// The alert breaches when any mountpoint crosses its threshold and
+	// clears when all of the mountpoints have reached their clear thresholds
+	breached := false
+	var exceeded []DiskMount
+	for _, d := range usage.Disks {
+		t, ok := a.threshold(d)
+		if !ok {
+			continue
+		}
+		if t.exceeded(d) {
+			breached = true
+		}
+		if !t.cleared(d) {
+			exceeded = append(exceeded, d)
+		}
+	}
+
+	a.rw.Lock()
+	defer a.rw.Unlock()
+	a.Exceeded = exceeded
+	return a.Hysteresis.apply(breached, len(exceeded) == 0)
+}
+
+// threshold returns the threshold of the mountpoint, false is returned if there is none.
+// Thresholds for a named mountpoint takes precedence over the wildcard
+func (a Disk) threshold(d DiskMount) (DiskThreshold, bool) {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+
+	var wildcard *DiskThreshold
+	for i, t := range a.Thresholds {
+		if !t.match(d.Mountpoint) {
+			continue
+		}
+		if t.Mountpoint == "*" {
+			if wildcard == nil {
+				wildcard = &a.Thresholds[i]
+			}
+			continue
+		}
+		return t, true
+	}
+
+	if wildcard != nil {
+		return *wildcard, true
+	}
+	return DiskThreshold{}, false
+}
+
+func (a Disk) GetExceeded() []DiskMount {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return a.Exceeded
+}
+
+func (a Disk) Value() string {
+	var values []string
+	for _, d := range a.GetExceeded() {
+		values = append(values, d.Mountpoint+":"+strconv.FormatFloat(d.procent(), 'f', 1, 64))
+	}
+	return strings.Join(values, ",")
+}
+
+// Threshold returns the thresholds of the mountpoints written like "/var:90%,/:5GB"
+func (a Disk) Threshold() string {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	thresholds := make([]string, len(a.Thresholds))
+	for i, t := range a.Thresholds {
+		if t.Bytes {
+			thresholds[i] = t.Mountpoint + ":" + formatBytes(t.Free)
+		} else {
+			thresholds[i] = t.Mountpoint + ":" + strconv.FormatFloat(t.Procent, 'f', -1, 64) + "%"
+		}
+	}
+	return strings.Join(thresholds, ",")
+}
+
+func (a Disk) Message() string {
+	var msgs []string
+	for _, d := range a.GetExceeded() {
+		t, _ := a.threshold(d)
+		msgs = append(msgs, fmt.Sprintf("%s at %.1f%% with %s free (limit %s)", d.Mountpoint, d.procent(), formatBytes(d.Free), t))
+	}
+	return fmt.Sprintf("Disk Usage: %s", strings.Join(msgs, ", "))
+}
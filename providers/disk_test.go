@@ -0,0 +1,85 @@
+package providers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDiskThresholds(t *testing.T) {
+	tests := []struct {
+		value      string
+		thresholds []DiskThreshold
+	}{
+		{"90", []DiskThreshold{{Mountpoint: "*", Procent: 90, ClearProcent: 90}}},
+		{"/var:90%", []DiskThreshold{{Mountpoint: "/var", Procent: 90, ClearProcent: 90}}},
+		{"/var:90/85, /:5GB", []DiskThreshold{
+			{Mountpoint: "/var", Procent: 90, ClearProcent: 85},
+			{Mountpoint: "/", Bytes: true, Free: 5 << 30, ClearFree: 5 << 30},
+		}},
+		{"/:512MB/1GB;breach=2", []DiskThreshold{{Mountpoint: "/", Bytes: true, Free: 512 << 20, ClearFree: 1 << 30}}},
+		{"C::10kb,*:95", []DiskThreshold{
+			{Mountpoint: "C:", Bytes: true, Free: 10 << 10, ClearFree: 10 << 10},
+			{Mountpoint: "*", Procent: 95, ClearProcent: 95},
+		}},
+	}
+
+	for _, test := range tests {
+		thresholds, err := ParseDiskThresholds(test.value)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(thresholds, test.thresholds) {
+			t.Errorf("%q: got %+v, expected %+v", test.value, thresholds, test.thresholds)
+		}
+	}
+}
+
+func TestParseDiskThresholdsErrors(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"/var:",
+		"/var:full",
+		"/var:10XB",
+		"/var:0",
+		"/var:0%",
+		"/:0B",
+		"/:0GB",
+		"/var:90/95",
+		"/:5GB/1GB",
+		"/var:90/1GB",
+		"/:5GB/80",
+		"/var:90;clear=85",
+	} {
+		if _, err := ParseDiskThresholds(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestDiskCheck(t *testing.T) {
+	p, err := NewAlertProviderDisk("/:90/80,/data:1GB")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		resp   string
+		firing bool
+	}{
+		{`{"disks":[{"mountpoint":"/","procent":85},{"mountpoint":"/data","free":2147483648}]}`, false},
+		{`{"disks":[{"mountpoint":"/","procent":95},{"mountpoint":"/data","free":2147483648}]}`, true},
+		// Below the threshold but above the clear threshold keeps firing
+		{`{"disks":[{"mountpoint":"/","procent":85},{"mountpoint":"/data","free":2147483648}]}`, true},
+		{`{"disks":[{"mountpoint":"/","procent":75},{"mountpoint":"/data","free":2147483648}]}`, false},
+		{`{"disks":[{"mountpoint":"/","procent":75},{"mountpoint":"/data","free":1024}]}`, true},
+		{`{"disks":[{"mountpoint":"/","procent":75},{"mountpoint":"/data","free":1073741824}]}`, false},
+		{`{"error":"no disks"}`, false},
+	}
+
+	for i, test := range tests {
+		if firing := p.Check(test.resp); firing != test.firing {
+			t.Errorf("check %d: firing is %t, expected %t", i+1, firing, test.firing)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package providers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseOptions splits an alert option value like "80;clear=70;breach=3" into
+// the main value and a map of the named options
+func ParseOptions(value string) (string, map[string]string) {
+	options := map[string]string{}
+	parts := strings.Split(value, ";")
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(p, "=", 2)
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		if key == "" {
+			continue
+		}
+		if len(kv) == 2 {
+			options[key] = strings.TrimSpace(kv[1])
+		} else {
+			options[key] = ""
+		}
+	}
+	return strings.TrimSpace(parts[0]), options
+}
+
+// Hysteresis keeps track of the state for threshold providers, the state only
+// changes after the threshold or the clear threshold has been crossed for
+// enough consecutive samples
+type Hysteresis struct {
+	Clear   float64 // The value has to drop to or below this before the alert clears
+	Breach  int     // Consecutive samples above the threshold before the alert fires
+	Recover int     // Consecutive samples at or below the clear threshold before the alert clears
+
+	firing     bool
+	breaches   int
+	recoveries int
+}
+
+// ParseThreshold parses a threshold value like "80;clear=70;breach=3;recover=2",
+// the clear threshold defaults to the threshold and breach/recover defaults to 1
+func ParseThreshold(value string) (float64, Hysteresis, error) {
+	v, options := ParseOptions(value)
+	threshold, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, Hysteresis{}, fmt.Errorf("the threshold: %s can't be converted to a number", v)
+	}
+
+	h, err := parseCounts(options)
+	if err != nil {
+		return 0, h, err
+	}
+	h.Clear = threshold
+	if c, ok := options["clear"]; ok {
+		h.Clear, err = strconv.ParseFloat(c, 64)
+		if err != nil {
+			return 0, h, fmt.Errorf("the clear threshold: %s can't be converted to a number", c)
+		}
+		if h.Clear > threshold {
+			return 0, h, fmt.Errorf("the clear threshold: %s is larger then the threshold: %s", c, v)
+		}
+	}
+	return threshold, h, nil
+}
+
+// parseCounts parses the "breach" and "recover" options, both defaults to 1
+func parseCounts(options map[string]string) (Hysteresis, error) {
+	var err error
+	h := Hysteresis{Breach: 1, Recover: 1}
+	if b, ok := options["breach"]; ok {
+		if h.Breach, err = strconv.Atoi(b); err != nil || h.Breach < 1 {
+			return h, fmt.Errorf("the breach count: %s has to be a positive integer", b)
+		}
+	}
+	if r, ok := options["recover"]; ok {
+		if h.Recover, err = strconv.Atoi(r); err != nil || h.Recover < 1 {
+			return h, fmt.Errorf("the recover count: %s has to be a positive integer", r)
+		}
+	}
+	return h, nil
+}
+
+// update adds a new sample and returns whether the alert is firing or not
+func (h *Hysteresis) update(value, threshold float64) bool {
+	return h.apply(value > threshold, value <= h.Clear)
+}
+
+// apply adds a new sample that either breached the threshold, reached the clear threshold or
+// neither of them and returns whether the alert is firing or not
+func (h *Hysteresis) apply(breached, cleared bool) bool {
+	switch {
+	case breached:
+		h.breaches++
+		h.recoveries = 0
+	case cleared:
+		h.recoveries++
+		h.breaches = 0
+	default:
+		// Between the clear threshold and the threshold, keep the current state
+		h.breaches = 0
+		h.recoveries = 0
+	}
+
+	if !h.firing && h.breaches >= h.Breach {
+		h.firing = true
+	} else if h.firing && h.recoveries >= h.Recover {
+		h.firing = false
+	}
+	return h.firing
+}
+
+// setOptions modifies the thresholds and counts without resetting the current state
+func (h *Hysteresis) setOptions(o Hysteresis) {
+	h.Clear = o.Clear
+	h.Breach = o.Breach
+	h.Recover = o.Recover
+}
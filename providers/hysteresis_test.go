@@ -0,0 +1,102 @@
+package providers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		value   string
+		main    string
+		options map[string]string
+	}{
+		{"80", "80", map[string]string{}},
+		{" 80 ;clear=70", "80", map[string]string{"clear": "70"}},
+		{"80;Clear = 70;breach=3;recover=2", "80", map[string]string{"clear": "70", "breach": "3", "recover": "2"}},
+		{"email;contacts=ops,dba;urgent", "email", map[string]string{"contacts": "ops,dba", "urgent": ""}},
+		{"a;;=1;b=c=d", "a", map[string]string{"b": "c=d"}},
+		{"", "", map[string]string{}},
+	}
+
+	for _, test := range tests {
+		main, options := ParseOptions(test.value)
+		if main != test.main {
+			t.Errorf("%q: main value is %q, expected %q", test.value, main, test.main)
+		}
+		if !reflect.DeepEqual(options, test.options) {
+			t.Errorf("%q: options are %v, expected %v", test.value, options, test.options)
+		}
+	}
+}
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		value      string
+		threshold  float64
+		hysteresis Hysteresis
+		agg        Aggregation // The aggregation that is read from the same value, not checked if empty
+		err        bool
+	}{
+		{value: "80;clear=70", threshold: 80, hysteresis: Hysteresis{Clear: 70, Breach: 1, Recover: 1}},
+		{value: "80.5;clear=70;breach=3;recover=2", threshold: 80.5, hysteresis: Hysteresis{Clear: 70, Breach: 3, Recover: 2}},
+		{value: "80", threshold: 80, hysteresis: Hysteresis{Clear: 80, Breach: 1, Recover: 1}, agg: AggregationAvg},
+		{value: "80;clear=70;agg=p95", threshold: 80, hysteresis: Hysteresis{Clear: 70, Breach: 1, Recover: 1}, agg: "p95"},
+		{value: "high", err: true},
+		{value: "80;clear=90", err: true},
+		{value: "80;clear=low", err: true},
+		{value: "80;breach=0", err: true},
+		{value: "80;breach=two", err: true},
+		{value: "80;recover=-1", err: true},
+	}
+
+	for _, test := range tests {
+		threshold, h, err := ParseThreshold(test.value)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", test.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.value, err)
+			continue
+		}
+		if threshold != test.threshold || h != test.hysteresis {
+			t.Errorf("%q: got %v %+v, expected %v %+v", test.value, threshold, h, test.threshold, test.hysteresis)
+		}
+		if test.agg != "" {
+			if agg, err := ParseAggregation(test.value); err != nil || agg != test.agg {
+				t.Errorf("%q: aggregation is %q %v, expected %q", test.value, agg, err, test.agg)
+			}
+		}
+	}
+}
+
+func TestHysteresisUpdate(t *testing.T) {
+	_, h, err := ParseThreshold("80;clear=70;breach=2;recover=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		value  float64
+		firing bool
+	}{
+		{85, false},
+		{75, false}, // Between the thresholds resets the breaches
+		{85, false},
+		{85, true},
+		{75, true},
+		{65, true},
+		{75, true}, // Between the thresholds resets the recoveries
+		{65, true},
+		{65, false},
+	}
+
+	for i, test := range tests {
+		if firing := h.update(test.value, 80); firing != test.firing {
+			t.Errorf("sample %d (%v): firing is %t, expected %t", i+1, test.value, firing, test.firing)
+		}
+	}
+}
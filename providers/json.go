@@ -0,0 +1,238 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSON operators that can be used when comparing a field with the threshold
+var jsonOperators = []string{">", ">=", "<", "<=", "==", "!=", "contains", "regex"}
+
+// JSONCondition is a condition on a field in the check response,
+// the condition is written like "path operator threshold" for example
+// "disks.0.procent >= 90" or "status regex ^(down|failed)$"
+type JSONCondition struct {
+	Path      []string
+	Operator  string
+	Threshold string
+	Clear     string // The condition clears when the value no longer matches this threshold, the threshold is used if empty
+	re        *regexp.Regexp
+}
+
+// ParseJSONCondition parses a condition string like "memory.procent > 90;clear=80", a clear
+// threshold can only be used with the >, >=, < and <= operators
+func ParseJSONCondition(value string) (*JSONCondition, error) {
+	value, options := ParseOptions(value)
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("the condition: %s should be written as \"path operator threshold\"", value)
+	}
+
+	c := &JSONCondition{
+		Path:      strings.Split(fields[0], "."),
+		Operator:  fields[1],
+		Threshold: strings.Join(fields[2:], " "),
+	}
+
+	valid := false
+	for _, op := range jsonOperators {
+		if op == c.Operator {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("the operator: %s is not one of %s", c.Operator, strings.Join(jsonOperators, ", "))
+	}
+
+	clear, hasClear := options["clear"]
+	switch c.Operator {
+	case ">", ">=", "<", "<=":
+		threshold, err := strconv.ParseFloat(c.Threshold, 64)
+		if err != nil {
+			return nil, fmt.Errorf("the threshold: %s can't be converted to a number", c.Threshold)
+		}
+		if !hasClear {
+			break
+		}
+		f, err := strconv.ParseFloat(clear, 64)
+		if err != nil {
+			return nil, fmt.Errorf("the clear threshold: %s can't be converted to a number", clear)
+		}
+		// The clear threshold has to be on the other side of the threshold
+		if strings.HasPrefix(c.Operator, ">") && f > threshold {
+			return nil, fmt.Errorf("the clear threshold: %s is larger then the threshold: %s", clear, c.Threshold)
+		}
+		if strings.HasPrefix(c.Operator, "<") && f < threshold {
+			return nil, fmt.Errorf("the clear threshold: %s is smaller then the threshold: %s", clear, c.Threshold)
+		}
+		c.Clear = clear
+	case "regex":
+		re, err := regexp.Compile(c.Threshold)
+		if err != nil {
+			return nil, fmt.Errorf("the threshold: %s is not a valid regex", c.Threshold)
+		}
+		c.re = re
+	}
+	if hasClear && c.Clear == "" {
+		return nil, fmt.Errorf("a clear threshold can't be used with the operator: %s", c.Operator)
+	}
+	return c, nil
+}
+
+// Lookup finds the value of the path in the decoded response, array elements are accessed by index
+func (c JSONCondition) Lookup(data interface{}) (interface{}, bool) {
+	return lookupJSON(data, c.Path)
+}
+
+func lookupJSON(data interface{}, path []string) (interface{}, bool) {
+	for _, p := range path {
+		switch v := data.(type) {
+		case map[string]interface{}:
+			d, ok := v[p]
+			if !ok {
+				return nil, false
+			}
+			data = d
+		case []interface{}:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			data = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// Match compares the value with the threshold
+func (c JSONCondition) Match(value interface{}) bool {
+	return c.match(value, c.Threshold)
+}
+
+// Cleared returns whether the value no longer matches the clear threshold
+func (c JSONCondition) Cleared(value interface{}) bool {
+	if c.Clear == "" {
+		return !c.Match(value)
+	}
+	return !c.match(value, c.Clear)
+}
+
+func (c JSONCondition) match(value interface{}, t string) bool {
+	s := jsonString(value)
+	f, numErr := strconv.ParseFloat(s, 64)
+	threshold, thresholdErr := strconv.ParseFloat(t, 64)
+	numeric := numErr == nil && thresholdErr == nil
+
+	switch c.Operator {
+	case ">":
+		return numeric && f > threshold
+	case ">=":
+		return numeric && f >= threshold
+	case "<":
+		return numeric && f < threshold
+	case "<=":
+		return numeric && f <= threshold
+	case "==":
+		if numeric {
+			return f == threshold
+		}
+		return s == t
+	case "!=":
+		if numeric {
+			return f != threshold
+		}
+		return s != t
+	case "contains":
+		return strings.Contains(s, t)
+	case "regex":
+		return c.re.MatchString(s)
+	}
+	return false
+}
+
+func (c JSONCondition) String() string {
+	if c.Clear != "" {
+		return fmt.Sprintf("%s %s %s, clear %s", strings.Join(c.Path, "."), c.Operator, c.Threshold, c.Clear)
+	}
+	return fmt.Sprintf("%s %s %s", strings.Join(c.Path, "."), c.Operator, c.Threshold)
+}
+
+func jsonString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return "null"
+	}
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+type JSON struct {
+	rw         *sync.RWMutex
+	Condition  *JSONCondition
+	Total      int // Amount of consecutive matches before alerting, used when no breach count is set
+	Current    string
+	Hysteresis Hysteresis
+}
+
+func (a JSON) Name() string {
+	return "JSON"
+}
+
+func (a *JSON) Check(resp string) bool {
+	var data interface{}
+	if err := json.Unmarshal([]byte(resp), &data); err != nil {
+		return false
+	}
+
+	a.rw.Lock()
+	defer a.rw.Unlock()
+
+	// A missing value counts as cleared
+	value, ok := a.Condition.Lookup(data)
+	if !ok {
+		a.Current = ""
+		return a.Hysteresis.apply(false, true)
+	}
+
+	a.Current = jsonString(value)
+	return a.Hysteresis.apply(a.Condition.Match(value), a.Condition.Cleared(value))
+}
+
+func (a JSON) GetCondition() *JSONCondition {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return a.Condition
+}
+
+func (a JSON) Value() string {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return a.Current
+}
+
+// Threshold returns the operator and threshold of the condition like "> 90"
+func (a JSON) Threshold() string {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	if a.Condition == nil {
+		return ""
+	}
+	return a.Condition.Operator + " " + a.Condition.Threshold
+}
+
+func (a JSON) Message() string {
+	return fmt.Sprintf("%s: %s (condition %s)", strings.Join(a.GetCondition().Path, "."), a.Value(), a.GetCondition())
+}
@@ -0,0 +1,126 @@
+package providers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONCondition(t *testing.T) {
+	tests := []struct {
+		value     string
+		condition JSONCondition
+	}{
+		{"memory.procent > 90", JSONCondition{Path: []string{"memory", "procent"}, Operator: ">", Threshold: "90"}},
+		{"load.1 >= 4;clear=2", JSONCondition{Path: []string{"load", "1"}, Operator: ">=", Threshold: "4", Clear: "2"}},
+		{"free < 10;clear=20;recover=2", JSONCondition{Path: []string{"free"}, Operator: "<", Threshold: "10", Clear: "20"}},
+		{"status == running late", JSONCondition{Path: []string{"status"}, Operator: "==", Threshold: "running late"}},
+		{"disks.0.name contains sd", JSONCondition{Path: []string{"disks", "0", "name"}, Operator: "contains", Threshold: "sd"}},
+	}
+
+	for _, test := range tests {
+		c, err := ParseJSONCondition(test.value)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(*c, test.condition) {
+			t.Errorf("%q: got %+v, expected %+v", test.value, *c, test.condition)
+		}
+	}
+}
+
+func TestParseJSONConditionErrors(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"memory.procent >",
+		"memory.procent => 90",
+		"memory.procent > high",
+		"status regex ([a-z]",
+		"load > 4;clear=6",
+		"free < 10;clear=5",
+		"load > 4;clear=low",
+		"status == down;clear=up",
+		"load > 4;breach=0",
+	} {
+		if _, err := NewAlertProviderJSON(1, value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestJSONConditionMatch(t *testing.T) {
+	tests := []struct {
+		condition string
+		value     string
+		match     bool
+		cleared   bool
+	}{
+		{"v > 90", `95`, true, false},
+		{"v > 90", `90`, false, true},
+		{"v > 90;clear=80", `85`, false, false},
+		{"v > 90;clear=80", `80`, false, true},
+		{"v <= 10;clear=20", `15`, false, false},
+		{"v <= 10;clear=20", `25`, false, true},
+		{"v > 90", `"95"`, true, false},
+		{"v > 90", `"high"`, false, true},
+		{"v == 1", `1.0`, true, false},
+		{"v == up", `"up"`, true, false},
+		{"v != up", `"down"`, true, false},
+		{"v == true", `true`, true, false},
+		{"v == null", `null`, true, false},
+		{"v contains err", `"stderr"`, true, false},
+		{"v regex ^(down|failed)$", `"failed"`, true, false},
+		{"v regex ^(down|failed)$", `"failed again"`, false, true},
+	}
+
+	for _, test := range tests {
+		c, err := ParseJSONCondition(test.condition)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.condition, err)
+			continue
+		}
+		var value interface{}
+		if err = json.Unmarshal([]byte(test.value), &value); err != nil {
+			t.Fatal(err)
+		}
+		if match := c.Match(value); match != test.match {
+			t.Errorf("%q with %s: match is %t, expected %t", test.condition, test.value, match, test.match)
+		}
+		if cleared := c.Cleared(value); cleared != test.cleared {
+			t.Errorf("%q with %s: cleared is %t, expected %t", test.condition, test.value, cleared, test.cleared)
+		}
+	}
+}
+
+func TestJSONLookup(t *testing.T) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(`{"disks":[{"name":"sda","procent":42}],"ok":true}`), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path  string
+		value interface{}
+		found bool
+	}{
+		{"disks.0.procent > 0", float64(42), true},
+		{"disks.0.name == sda", "sda", true},
+		{"ok == true", true, true},
+		{"disks.1.procent > 0", nil, false},
+		{"disks.x.procent > 0", nil, false},
+		{"ok.value > 0", nil, false},
+		{"missing > 0", nil, false},
+	}
+
+	for _, test := range tests {
+		c, err := ParseJSONCondition(test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, found := c.Lookup(data)
+		if found != test.found || !reflect.DeepEqual(value, test.value) {
+			t.Errorf("%q: got %v %t, expected %v %t", test.path, value, found, test.value, test.found)
+		}
+	}
+}
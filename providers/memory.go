@@ -0,0 +1,53 @@
+package providers
+
+import "encoding/json"
+
+// MemoryUsage contains information about the memory and swap usage
+type MemoryUsage struct {
+	Error       string  `json:"error"`
+	Total       uint64  `json:"total"`
+	Used        uint64  `json:"used"`
+	Procent     float64 `json:"procent"`
+	SwapTotal   uint64  `json:"swap_total"`
+	SwapUsed    uint64  `json:"swap_used"`
+	SwapProcent float64 `json:"swap_procent"`
+}
+
+// procent returns the memory or swap usage in percent, if the client didn't
+// send a percentage it will be calculated from used and total
+func (m MemoryUsage) procent(swap bool) float64 {
+	used, total, procent := m.Used, m.Total, m.Procent
+	if swap {
+		used, total, procent = m.SwapUsed, m.SwapTotal, m.SwapProcent
+	}
+
+	if procent == 0 && total > 0 {
+		return float64(used) / float64(total) * 100
+	}
+	return procent
+}
+
+type Memory struct {
+	Window
+	Swap bool
+}
+
+func (a Memory) Name() string {
+	if a.Swap {
+		return "Swap"
+	}
+	return "Memory"
+}
+
+func (a *Memory) Check(resp string) bool {
+	usage := MemoryUsage{}
+	err := json.Unmarshal([]byte(resp), &usage)
+	if err != nil || usage.Error != "" {
+		return false
+	}
+	return a.add(usage.procent(a.Swap))
+}
+
+func (a Memory) Message() string {
+	return a.usage(a.Name())
+}
@@ -0,0 +1,140 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PortResponse is the response from a ping check, the error is set if any of the ports are closed
+type PortResponse struct {
+	Error string       `json:"error"`
+	Ports []PortResult `json:"ports"`
+}
+
+// PortResult mirrors models.PingResult, the result of a single port
+type PortResult struct {
+	Port   uint16
+	Result bool
+}
+
+// ParsePorts parses a comma separated list of ports and port ranges like "80,443,8000-8010"
+func ParsePorts(value string) ([]uint16, error) {
+	var ports []uint16
+	for _, p := range strings.Split(strings.Replace(value, " ", "", -1), ",") {
+		if p == "" {
+			continue
+		}
+		pSplit := strings.Split(p, "-")
+
+		minPort, err := strconv.ParseUint(pSplit[0], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("the value: %s can't be converted to a port", pSplit[0])
+		}
+		maxPort := minPort
+
+		if len(pSplit) == 2 {
+			maxPort, err = strconv.ParseUint(pSplit[1], 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("the value: %s can't be converted to a port", pSplit[1])
+			}
+		}
+
+		if minPort > maxPort {
+			return nil, fmt.Errorf("min value \"%d\" is larger then the max value \"%d\"", minPort, maxPort)
+		}
+
+		for i := minPort; i <= maxPort; i++ {
+			ports = append(ports, uint16(i))
+		}
+	}
+	return ports, nil
+}
+
+type Port struct {
+	rw       *sync.RWMutex
+	Ports    []uint16 // Required ports, all ports in the response is required if empty
+	Total    int      // Amount of consecutive failed checks before alerting
+	Failures int
+	Down     []uint16
+}
+
+func (a Port) Name() string {
+	return "Port"
+}
+
+func (a *Port) Check(resp string) bool {
+	response := PortResponse{}
+	err := json.Unmarshal([]byte(resp), &response)
+	if err != nil || len(response.Ports) == 0 {
+		return false
+	}
+
+	var down []uint16
+	for _, p := range response.Ports {
+		if !p.Result && a.required(p.Port) {
+			down = append(down, p.Port)
+		}
+	}
+
+	a.rw.Lock()
+	defer a.rw.Unlock()
+	a.Down = down
+	if len(down) == 0 {
+		a.Failures = 0
+		return false
+	}
+	a.Failures++
+	return a.Failures >= a.Total
+}
+
+func (a Port) required(port uint16) bool {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	if len(a.Ports) == 0 {
+		return true
+	}
+	for _, p := range a.Ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Port) GetDown() []uint16 {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return a.Down
+}
+
+func (a Port) GetFailures() int {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return a.Failures
+}
+
+func (a Port) Value() string {
+	var ports []string
+	for _, p := range a.GetDown() {
+		ports = append(ports, strconv.Itoa(int(p)))
+	}
+	return strings.Join(ports, ",")
+}
+
+// Threshold returns the required ports, empty if all ports in the response are required
+func (a Port) Threshold() string {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	ports := make([]string, len(a.Ports))
+	for i, p := range a.Ports {
+		ports[i] = strconv.Itoa(int(p))
+	}
+	return strings.Join(ports, ",")
+}
+
+func (a Port) Message() string {
+	return fmt.Sprintf("Ports down: %s (%d checks in a row)", a.Value(), a.GetFailures())
+}
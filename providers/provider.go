@@ -1,7 +1,9 @@
 package providers
 
 import (
+	"strings"
 	"sync"
+	"time"
 )
 
 type AlertProvider interface {
@@ -11,10 +13,213 @@ type AlertProvider interface {
 	Name() string
 }
 
-func NewAlertProviderCPU(total int, avg float64) AlertProvider {
-	return &CPU{
+// ThresholdProvider is implemented by the providers that have a threshold, it's shown in the notifications
+type ThresholdProvider interface {
+	Threshold() string
+}
+
+func NewAlertProviderCPU(total int, avg float64, agg Aggregation, h Hysteresis) AlertProvider {
+	return &CPU{Window: newWindow(total, avg, agg, h)}
+}
+
+func NewAlertProviderMemory(total int, avg float64, swap bool, agg Aggregation, h Hysteresis) AlertProvider {
+	return &Memory{Window: newWindow(total, avg, agg, h), Swap: swap}
+}
+
+func NewAlertProviderDisk(thresholds string) (AlertProvider, error) {
+	t, err := ParseDiskThresholds(thresholds)
+	if err != nil {
+		return nil, err
+	}
+	_, options := ParseOptions(thresholds)
+	h, err := parseCounts(options)
+	if err != nil {
+		return nil, err
+	}
+	return &Disk{
+		rw:         new(sync.RWMutex),
+		Thresholds: t,
+		Hysteresis: h,
+	}, nil
+}
+
+func NewAlertProviderPort(total int, ports string) (AlertProvider, error) {
+	p, err := ParsePorts(ports)
+	if err != nil {
+		return nil, err
+	}
+	return &Port{
 		rw:    new(sync.RWMutex),
+		Ports: p,
 		Total: total,
-		Avg:   avg,
+	}, nil
+}
+
+func NewAlertProviderJSON(total int, condition string) (AlertProvider, error) {
+	c, err := ParseJSONCondition(condition)
+	if err != nil {
+		return nil, err
+	}
+	_, options := ParseOptions(condition)
+	h, err := parseCounts(options)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := options["breach"]; !ok && total > 1 {
+		h.Breach = total
 	}
+	return &JSON{
+		rw:         new(sync.RWMutex),
+		Condition:  c,
+		Total:      total,
+		Hysteresis: h,
+	}, nil
+}
+
+func NewAlertProviderDeadman(total int, timeout string) (AlertProvider, error) {
+	t, err := ParseTimeout(timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Deadman{
+		rw:        new(sync.RWMutex),
+		Timeout:   t,
+		Total:     total,
+		Created:   time.Now(),
+		Connected: true,
+	}, nil
+}
+
+func NewAlertProviderRate(total int, value string) (AlertProvider, error) {
+	path, limit, full, max, err := ParseRate(value)
+	if err != nil {
+		return nil, err
+	}
+	return &Rate{
+		rw:    new(sync.RWMutex),
+		Path:  path,
+		Total: total,
+		Limit: limit,
+		Full:  full,
+		Max:   max,
+	}, nil
+}
+
+func NewAlertProviderComposite(expression string) (AlertProvider, error) {
+	e, err := ParseExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	return &Composite{
+		rw:         new(sync.RWMutex),
+		Expression: e,
+	}, nil
+}
+
+func NewAlertProviderAnomaly(value string) (AlertProvider, error) {
+	path, sigma, alpha, warmup, seasonal, err := ParseAnomaly(value)
+	if err != nil {
+		return nil, err
+	}
+	return &Anomaly{
+		rw:        new(sync.RWMutex),
+		Path:      path,
+		Sigma:     sigma,
+		Alpha:     alpha,
+		Warmup:    warmup,
+		Seasonal:  seasonal,
+		Baselines: map[int]*Baseline{},
+	}, nil
+}
+
+// KeepHistory copies the history of the old provider into the new provider so an updated alert doesn't
+// start over, the new provider keeps its own settings. Nothing is copied if the providers are of different
+// types or the history isn't valid for the new settings. The new provider can't be in use yet
+func KeepHistory(p, old AlertProvider) {
+	switch n := p.(type) {
+	case *CPU:
+		if o, ok := old.(*CPU); ok {
+			n.keep(&o.Window)
+		}
+	case *Memory:
+		if o, ok := old.(*Memory); ok && o.Swap == n.Swap {
+			n.keep(&o.Window)
+		}
+	case *Disk:
+		if o, ok := old.(*Disk); ok {
+			o.rw.RLock()
+			defer o.rw.RUnlock()
+			n.Exceeded = o.Exceeded
+			n.Hysteresis = keepState(o.Hysteresis, n.Hysteresis)
+		}
+	case *Port:
+		if o, ok := old.(*Port); ok {
+			o.rw.RLock()
+			defer o.rw.RUnlock()
+			n.Failures = o.Failures
+			n.Down = append([]uint16(nil), o.Down...)
+		}
+	case *JSON:
+		if o, ok := old.(*JSON); ok {
+			o.rw.RLock()
+			defer o.rw.RUnlock()
+			n.Current = o.Current
+			n.Hysteresis = keepState(o.Hysteresis, n.Hysteresis)
+		}
+	case *Deadman:
+		if o, ok := old.(*Deadman); ok {
+			o.rw.RLock()
+			defer o.rw.RUnlock()
+			n.Created = o.Created
+			n.LastSuccess = o.LastSuccess
+			n.Interval = o.Interval
+			n.Connected = o.Connected
+			n.Disconnected = o.Disconnected
+		}
+	case *Rate:
+		// The samples are only valid for the same value
+		if o, ok := old.(*Rate); ok && strings.Join(o.Path, ".") == strings.Join(n.Path, ".") {
+			o.rw.RLock()
+			defer o.rw.RUnlock()
+			n.Samples = append([]Sample(nil), o.Samples...)
+			if n.Total > 0 && len(n.Samples) > n.Total {
+				n.Samples = n.Samples[len(n.Samples)-n.Total:]
+			}
+			n.Current = o.Current
+			n.Estimate = o.Estimate
+		}
+	case *Composite:
+		if o, ok := old.(*Composite); ok {
+			o.rw.RLock()
+			defer o.rw.RUnlock()
+			n.States = o.States
+		}
+	case *Anomaly:
+		// The learned baseline is only valid for the same value and seasonality
+		if o, ok := old.(*Anomaly); ok && strings.Join(o.Path, ".") == strings.Join(n.Path, ".") && o.Seasonal == n.Seasonal {
+			o.rw.RLock()
+			defer o.rw.RUnlock()
+			for bucket, b := range o.Baselines {
+				baseline := *b
+				n.Baselines[bucket] = &baseline
+			}
+			n.Current = o.Current
+			n.Expected = o.Expected
+			n.Deviation = o.Deviation
+		}
+	}
+}
+
+// lastValues returns a copy of the last total values
+func lastValues(values []float64, total int) []float64 {
+	if total > 0 && len(values) > total {
+		values = values[len(values)-total:]
+	}
+	return append([]float64(nil), values...)
+}
+
+// keepState returns the new hysteresis settings with the current state of the old hysteresis
+func keepState(old, h Hysteresis) Hysteresis {
+	old.setOptions(h)
+	return old
 }
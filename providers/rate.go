@@ -0,0 +1,185 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sample is a single value from a check response and when it was received
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// Rate fires when a value changes too fast, the value is found with a json path
+// and the rate is calculated per minute over the window. The option is written like
+// "disks.0.procent;rate=0.5" to fire when the value grows more then 0.5 per minute or
+// "disks.0.procent;full=6h;max=100" to fire when the value is projected to reach max within 6 hours
+type Rate struct {
+	rw       *sync.RWMutex
+	Path     []string
+	Samples  []Sample
+	Total    int           // Amount of samples in the window
+	Limit    float64       // Maximum change per minute, ignored if zero
+	Full     time.Duration // Fire if the value is projected to reach Max within this duration, ignored if zero
+	Max      float64
+	Current  float64       // Change per minute for the current window
+	Estimate time.Duration // Time until the value reaches Max, negative if it never will
+}
+
+// ParseRate parses a rate option like "queue.depth;rate=50" or "disks.0.procent;full=6h;max=100"
+func ParseRate(value string) (path []string, limit float64, full time.Duration, max float64, err error) {
+	p, options := ParseOptions(value)
+	if p == "" {
+		return nil, 0, 0, 0, fmt.Errorf("no json path was provided")
+	}
+	path = strings.Split(p, ".")
+	max = 100
+
+	if r, ok := options["rate"]; ok {
+		if limit, err = strconv.ParseFloat(r, 64); err != nil {
+			return nil, 0, 0, 0, fmt.Errorf("the rate: %s can't be converted to a number", r)
+		}
+		if limit < 0 {
+			return nil, 0, 0, 0, fmt.Errorf("the rate: %s can't be negative", r)
+		}
+	}
+	if f, ok := options["full"]; ok {
+		if full, err = ParseTimeout(f); err != nil {
+			return nil, 0, 0, 0, err
+		}
+		if full < 0 {
+			return nil, 0, 0, 0, fmt.Errorf("the full duration: %s can't be negative", f)
+		}
+	}
+	if m, ok := options["max"]; ok {
+		if max, err = strconv.ParseFloat(m, 64); err != nil {
+			return nil, 0, 0, 0, fmt.Errorf("the max: %s can't be converted to a number", m)
+		}
+	}
+
+	if limit == 0 && full == 0 {
+		return nil, 0, 0, 0, fmt.Errorf("either a rate or a full duration has to be provided")
+	}
+	return path, limit, full, max, nil
+}
+
+func (a Rate) Name() string {
+	return "Rate"
+}
+
+func (a *Rate) Check(resp string) bool {
+	var data interface{}
+	if err := json.Unmarshal([]byte(resp), &data); err != nil {
+		return false
+	}
+
+	a.rw.Lock()
+	defer a.rw.Unlock()
+
+	value, ok := lookupJSON(data, a.Path)
+	if !ok {
+		return false
+	}
+	f, err := strconv.ParseFloat(jsonString(value), 64)
+	if err != nil {
+		return false
+	}
+
+	total := a.Total
+	if total < 2 {
+		total = 2
+	}
+	a.Samples = append(a.Samples, Sample{Time: time.Now(), Value: f})
+	if len(a.Samples) > total {
+		a.Samples = a.Samples[len(a.Samples)-total:]
+	}
+	if len(a.Samples) < total {
+		return false
+	}
+
+	a.Current = slope(a.Samples)
+	a.Estimate = -1
+	if a.Current > 0 {
+		// Estimates too far away to fit in a duration are seen as never
+		minutes := math.Max((a.Max-f)/a.Current, 0)
+		if minutes < float64(math.MaxInt64/int64(time.Minute)) {
+			a.Estimate = time.Duration(minutes * float64(time.Minute))
+		}
+	}
+
+	// Only growth is compared with the limit, a value that drops fast doesn't fire
+	if a.Limit > 0 && a.Current > a.Limit {
+		return true
+	}
+	if a.Full != 0 && a.Estimate >= 0 && a.Estimate < a.Full {
+		return true
+	}
+	return false
+}
+
+// slope calculates the change per minute with a least squares linear regression
+func slope(samples []Sample) float64 {
+	n := float64(len(samples))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.Time.Sub(samples[0].Time).Minutes()
+		sumX += x
+		sumY += s.Value
+		sumXY += x * s.Value
+		sumXX += x * x
+	}
+
+	d := n*sumXX - sumX*sumX
+	if d == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / d
+}
+
+func (a Rate) GetCurrent() float64 {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return a.Current
+}
+
+func (a Rate) GetEstimate() time.Duration {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return a.Estimate
+}
+
+func (a Rate) Value() string {
+	return strconv.FormatFloat(a.GetCurrent(), 'f', 2, 64)
+}
+
+// Threshold returns the maximum change per minute or the duration the value may not reach max within
+func (a Rate) Threshold() string {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	var thresholds []string
+	if a.Limit > 0 {
+		thresholds = append(thresholds, strconv.FormatFloat(a.Limit, 'f', -1, 64)+"/min")
+	}
+	if a.Full > 0 {
+		thresholds = append(thresholds, fmt.Sprintf("%s within %s", strconv.FormatFloat(a.Max, 'f', -1, 64), a.Full))
+	}
+	return strings.Join(thresholds, ",")
+}
+
+func (a Rate) Message() string {
+	a.rw.RLock()
+	path, max := strings.Join(a.Path, "."), a.Max
+	a.rw.RUnlock()
+
+	msg := fmt.Sprintf("%s is changing %s per minute", path, a.Value())
+	if e := a.GetEstimate(); e >= 0 {
+		msg += fmt.Sprintf(", reaching %s in %s", strconv.FormatFloat(max, 'g', -1, 64), e.Truncate(time.Minute))
+	}
+	return msg
+}
@@ -0,0 +1,111 @@
+package providers
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSlope(t *testing.T) {
+	start := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	samples := func(values ...float64) []Sample {
+		var s []Sample
+		for i, v := range values {
+			s = append(s, Sample{Time: start.Add(time.Duration(i) * time.Minute), Value: v})
+		}
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		samples []Sample
+		slope   float64
+	}{
+		{"growing", samples(10, 20, 30), 10},
+		{"shrinking", samples(30, 25, 20, 15), -5},
+		{"flat", samples(42, 42, 42), 0},
+		{"noisy", samples(0, 12, 18, 30), 9.6},
+		{"same time", []Sample{{Time: start, Value: 1}, {Time: start, Value: 5}}, 0},
+	}
+
+	for _, test := range tests {
+		if slope := slope(test.samples); math.Abs(slope-test.slope) > 1e-9 {
+			t.Errorf("%s: slope is %v, expected %v", test.name, slope, test.slope)
+		}
+	}
+}
+
+func TestParseRateErrors(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"queue.depth",
+		"queue.depth;rate=fast",
+		"queue.depth;rate=-1",
+		"queue.depth;full=-1h",
+		"queue.depth;full=soon",
+		"queue.depth;full=6h;max=all",
+	} {
+		if _, err := NewAlertProviderRate(3, value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestRateCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		values   []float64 // One sample per minute, the last value is the checked response
+		firing   bool
+		estimate time.Duration // Checked if not zero, within a second
+	}{
+		{"below the rate", "v;rate=15", []float64{10, 20, 30}, false, 0},
+		{"above the rate", "v;rate=5", []float64{10, 20, 30}, true, 0},
+		{"shrinking faster then the rate", "v;rate=5", []float64{30, 20, 10}, false, 0},
+		{"full within the duration", "v;full=10m", []float64{10, 20, 30}, true, 7 * time.Minute},
+		{"full after the duration", "v;full=5m", []float64{10, 20, 30}, false, 7 * time.Minute},
+		{"full at a custom max", "v;full=5m;max=50", []float64{10, 20, 30}, true, 2 * time.Minute},
+		{"never full", "v;full=10m", []float64{30, 20, 10}, false, -1},
+		{"window not full", "v;rate=5", []float64{10, 30}, false, 0},
+	}
+
+	for _, test := range tests {
+		p, err := NewAlertProviderRate(3, test.value)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		r := p.(*Rate)
+
+		// The earlier samples are placed a minute apart before now
+		last := len(test.values) - 1
+		now := time.Now()
+		for i, v := range test.values[:last] {
+			r.Samples = append(r.Samples, Sample{Time: now.Add(time.Duration(i-last) * time.Minute), Value: v})
+		}
+
+		if firing := r.Check(`{"v":` + strconv.FormatFloat(test.values[last], 'f', -1, 64) + `}`); firing != test.firing {
+			t.Errorf("%s: firing is %t, expected %t", test.name, firing, test.firing)
+		}
+		if e := r.GetEstimate(); test.estimate < 0 && e >= 0 {
+			t.Errorf("%s: estimate is %s, expected never", test.name, e)
+		} else if test.estimate > 0 && (e-test.estimate > time.Second || test.estimate-e > time.Second) {
+			t.Errorf("%s: estimate is %s, expected %s", test.name, e, test.estimate)
+		}
+	}
+}
+
+func TestRateCheckWithoutData(t *testing.T) {
+	p, err := NewAlertProviderRate(2, "queue.depth;rate=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, resp := range []string{"", "timeout", `{"queue":{}}`, `{"queue":{"depth":"deep"}}`} {
+		if p.Check(resp) {
+			t.Errorf("%q: expected the alert not to fire", resp)
+		}
+	}
+	if n := len(p.(*Rate).Samples); n != 0 {
+		t.Errorf("%d samples were added from responses without a value", n)
+	}
+}
@@ -0,0 +1,112 @@
+package providers
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// Window keeps the last Total samples of a usage in percent and compares their aggregation
+// with the threshold, it's shared by the CPU and memory providers
+type Window struct {
+	rw     *sync.RWMutex
+	Values []float64
+	Avg    float64
+	Total  int
+
+	Aggregation Aggregation
+	Hysteresis  Hysteresis
+}
+
+func newWindow(total int, avg float64, agg Aggregation, h Hysteresis) Window {
+	// At least one value is needed to compare with the threshold
+	if total < 1 {
+		total = 1
+	}
+	return Window{
+		rw:          new(sync.RWMutex),
+		Total:       total,
+		Avg:         avg,
+		Aggregation: agg,
+		Hysteresis:  h,
+	}
+}
+
+// add adds a sample to the window and returns if the alert is firing
+func (w *Window) add(value float64) bool {
+	// Only the last Total values are kept, the window can shrink when the alert is updated
+	w.rw.Lock()
+	w.Values = append(w.Values, value)
+	if total := w.Total; total > 0 && len(w.Values) > total {
+		w.Values = w.Values[len(w.Values)-total:]
+	}
+	w.rw.Unlock()
+
+	aggregated := w.aggregate()
+	w.rw.Lock()
+	defer w.rw.Unlock()
+	return w.Hysteresis.update(aggregated, w.Avg)
+}
+
+// keep copies the samples and the hysteresis state of the old window
+func (w *Window) keep(old *Window) {
+	old.rw.RLock()
+	defer old.rw.RUnlock()
+	w.Values = lastValues(old.Values, w.Total)
+	w.Hysteresis = keepState(old.Hysteresis, w.Hysteresis)
+}
+
+// aggregate combines the values in the window, it returns 0 until the window is full
+func (w Window) aggregate() float64 {
+	if w.GetTotal() > w.CountValues() {
+		return 0
+	}
+	return w.GetAggregation().apply(w.GetValues())
+}
+
+func (w Window) GetValues() []float64 {
+	w.rw.RLock()
+	defer w.rw.RUnlock()
+	return w.Values
+}
+
+func (w Window) GetTotal() int {
+	w.rw.RLock()
+	defer w.rw.RUnlock()
+	return w.Total
+}
+
+func (w Window) GetAggregation() Aggregation {
+	w.rw.RLock()
+	defer w.rw.RUnlock()
+	return w.Aggregation
+}
+
+func (w Window) GetAvg() float64 {
+	w.rw.RLock()
+	defer w.rw.RUnlock()
+	return w.Avg
+}
+
+func (w Window) CountValues() int {
+	w.rw.RLock()
+	defer w.rw.RUnlock()
+	return len(w.Values)
+}
+
+func (w Window) Value() string {
+	return strconv.FormatFloat(w.aggregate(), 'g', -1, 64)
+}
+
+// Threshold returns the usage in percent the alert fires above
+func (w Window) Threshold() string {
+	return strconv.FormatFloat(w.GetAvg(), 'f', -1, 64) + "%"
+}
+
+// usage returns the message for the usage of name, the aggregation is shown unless it's the average
+func (w Window) usage(name string) string {
+	if g := w.GetAggregation(); g != AggregationAvg {
+		return fmt.Sprintf("%s Usage (%s): %s", name, g, w.Value())
+	}
+	return fmt.Sprintf("%s Usage: %s", name, w.Value())
+}
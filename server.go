@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/keiwi/server/models"
+	"github.com/keiwi/server/services"
 	"github.com/keiwi/utils/log"
 	"github.com/keiwi/utils/log/handlers/cli"
 	"github.com/keiwi/utils/log/handlers/file"
@@ -24,6 +25,8 @@ import (
 var (
 	configType string
 	manager    *models.Manager
+	dispatcher *services.Dispatcher
+	digest     *services.Digest
 	natsConn   *nats.Conn
 	tcpConn    net.Listener
 	kill       bool
@@ -54,6 +57,61 @@ func Start() {
 	manager = man
 	log.Info("Finished creating the manager")
 
+	t, err := services.LoadTemplates(viper.GetString("templates_dir"))
+	if err != nil {
+		log.WithError(err).Error("error loading notification templates, the default templates are used")
+	} else {
+		models.SetTemplates(t)
+	}
+
+	if viper.IsSet("quiet_hours") {
+		rules, err := services.LoadQuietRules()
+		if err != nil {
+			log.WithError(err).Error("error reading quiet hours, notifications are never held back")
+		} else {
+			models.SetQuietHours(services.NewQuietHours(rules))
+		}
+	}
+
+	if viper.IsSet("ratelimit") {
+		config, err := services.LoadThrottleConfig()
+		if err != nil {
+			log.WithError(err).Error("error reading rate limits, notifications are not rate limited")
+		} else {
+			models.SetThrottle(services.NewThrottle(config))
+		}
+	}
+
+	if viper.IsSet("digest") {
+		rules, err := services.LoadDigestConfig()
+		if err != nil {
+			log.WithError(err).Error("error reading digest rules, notifications are sent directly")
+		} else {
+			digest = services.NewDigest(rules, models.Deliver)
+			digest.Start()
+			models.SetDigest(digest)
+		}
+	}
+
+	log.Info("Starting notification dispatcher")
+	d, err := services.NewDispatcher(services.DispatcherConfig{
+		Dir:        viper.GetString("queue_dir"),
+		Retries:    viper.GetInt("queue_retries"),
+		Backoff:    time.Duration(viper.GetInt("queue_backoff")) * time.Second,
+		MaxBackoff: time.Duration(viper.GetInt("queue_max_backoff")) * time.Second,
+	}, models.NewService)
+	if err != nil {
+		Close()
+		log.WithError(err).Fatal("Something went wrong when creating the notification dispatcher")
+		return
+	}
+	d.OnStatus = publishNotificationStatus
+	d.Start()
+	dispatcher = d
+	models.SetDispatcher(d)
+	handleNotifications()
+	log.Info("Notification dispatcher started")
+
 	log.Info("Starting to listen for database changes")
 	handleDatabaseChanges()
 	log.Info("Listening for database changes")
@@ -65,11 +123,23 @@ func Start() {
 				return
 			}
 			go Loop(natsConn)
-			time.Sleep(time.Second * time.Duration(viper.GetInt("interval")))
+			time.Sleep(loopInterval("interval"))
 		}
 	}()
 	log.Info("Loop started")
 
+	log.Info("Starting escalation scheduler")
+	go func() {
+		for {
+			if kill {
+				return
+			}
+			go Escalate()
+			time.Sleep(loopInterval("escalation_interval"))
+		}
+	}()
+	log.Info("Escalation scheduler started")
+
 	log.Info("Configuring certificates")
 	cer, err := tls.LoadX509KeyPair("D:/ssh/server.crt", "D:/ssh/server.key")
 	if err != nil {
@@ -108,8 +178,31 @@ func Loop(conn *nats.Conn) {
 	}
 }
 
+// loopInterval returns the interval in seconds from the config key, it's at least a second so a loop never spins
+func loopInterval(key string) time.Duration {
+	seconds := viper.GetInt(key)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Escalate is the function for the escalation scheduler, it will escalate the firing alerts that nobody acknowledged
+func Escalate() {
+	for cl := range manager.IterClients() {
+		cl.Escalate()
+	}
+}
+
 func Close() {
 	kill = true
+	// The collected digests are queued before the dispatcher stops
+	if digest != nil {
+		digest.Stop()
+	}
+	if dispatcher != nil {
+		dispatcher.Stop()
+	}
 	if natsConn != nil {
 		natsConn.Close()
 	}
@@ -138,6 +231,27 @@ func ReadConfig() {
 	viper.SetDefault("password", GenerateRandomString(32))
 	viper.SetDefault("interval", 600)
 	viper.SetDefault("nats_delay", 10)
+	viper.SetDefault("flapping_changes", 5)
+	viper.SetDefault("flapping_window", 3600)
+	viper.SetDefault("escalation_interval", 30)
+
+	viper.SetDefault("templates_dir", "./templates")
+
+	viper.SetDefault("queue_dir", "./queue")
+	viper.SetDefault("queue_retries", 8)
+	viper.SetDefault("queue_backoff", 5)
+	viper.SetDefault("queue_max_backoff", 3600)
+
+	viper.SetDefault("sms.base", services.SMSBaseURL)
+	viper.SetDefault("sms.timeout", services.DefaultTimeout)
+
+	viper.SetDefault("email.port", services.DefaultEmailPort)
+	viper.SetDefault("email.tls", services.EmailTLSStartTLS)
+	viper.SetDefault("email.timeout", services.DefaultTimeout)
+
+	viper.SetDefault("webhook.timeout", services.DefaultTimeout)
+
+	viper.SetDefault("chat.timeout", services.DefaultTimeout)
 
 	if err := viper.ReadInConfig(); err != nil {
 		log.Debug("Config file not found, saving default")
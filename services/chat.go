@@ -0,0 +1,217 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// ChatAPIURL is the default API used to post messages when a token is configured
+const ChatAPIURL = "https://slack.com/api/chat.postMessage"
+
+// ChatConfig is the chat configuration read from the "chat" key in the server config. Messages are
+// posted to the incoming webhook URL, unless a token is configured, then the chat.postMessage API is
+// used instead so resolved messages can be threaded under the firing message
+type ChatConfig struct {
+	WebhookURL string `mapstructure:"webhook_url"`
+	APIURL     string `mapstructure:"api_url"`
+	Token      string `mapstructure:"token"`
+	Channel    string `mapstructure:"channel"`
+	Username   string `mapstructure:"username"`
+	IconEmoji  string `mapstructure:"icon_emoji"`
+	Timeout    int    `mapstructure:"timeout"` // In seconds
+}
+
+// LoadChatConfig reads the chat configuration from the server config
+func LoadChatConfig() (ChatConfig, error) {
+	var c ChatConfig
+	if err := viper.UnmarshalKey("chat", &c); err != nil {
+		return c, errors.Wrap(err, "error reading chat config")
+	}
+	// The defaults in the server config aren't used for the values missing from a partial "chat" block
+	if c.APIURL == "" {
+		c.APIURL = ChatAPIURL
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.Token == "" && c.WebhookURL == "" {
+		return c, errors.New("chat config needs either a webhook_url or a token")
+	}
+	if c.Token != "" && c.Channel == "" {
+		return c, errors.New("chat config needs a channel when using a token")
+	}
+	return c, nil
+}
+
+// ChatMessage is a Slack compatible message, it's also accepted by Mattermost
+type ChatMessage struct {
+	Channel     string           `json:"channel,omitempty"`
+	Username    string           `json:"username,omitempty"`
+	IconEmoji   string           `json:"icon_emoji,omitempty"`
+	Text        string           `json:"text,omitempty"`
+	ThreadTS    string           `json:"thread_ts,omitempty"`
+	Attachments []ChatAttachment `json:"attachments"`
+}
+
+type ChatAttachment struct {
+	Fallback string      `json:"fallback"`
+	Color    string      `json:"color"`
+	Title    string      `json:"title"`
+	Text     string      `json:"text"`
+	Fields   []ChatField `json:"fields"`
+	TS       int64       `json:"ts"`
+}
+
+type ChatField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// chatColor returns the attachment color for the state and severity of a notification
+func chatColor(n Notification) string {
+	switch n.State {
+	case "resolved":
+		return "good"
+	case "flapping":
+		return "#aaaaaa"
+	}
+	switch n.Severity {
+	case "critical":
+		return "danger"
+	case "warning":
+		return "warning"
+	}
+	return "#439fe0"
+}
+
+// The timestamps of the firing messages for every alert and client, they are kept outside of the
+// service since the services of an alert are recreated whenever the alert is updated
+var (
+	chatThreadsRW = new(sync.RWMutex)
+	chatThreads   = map[string]string{}
+)
+
+type Chat struct {
+	Config ChatConfig
+	Client *http.Client
+}
+
+func (Chat) Name() string {
+	return "chat"
+}
+
+func (c Chat) Send(n Notification) error {
+	title := n.Title
+	if n.Severity != "" {
+		title = fmt.Sprintf("[%s] %s", strings.ToUpper(n.Severity), n.Title)
+	}
+
+	m := ChatMessage{
+		Channel:   c.Config.Channel,
+		Username:  c.Config.Username,
+		IconEmoji: c.Config.IconEmoji,
+		Attachments: []ChatAttachment{{
+			Fallback: fmt.Sprintf("%s: %s", title, n.Message),
+			Color:    chatColor(n),
+			Title:    title,
+			Text:     n.Message,
+			Fields: []ChatField{
+				{Title: "Client", Value: n.ClientIP, Short: true},
+				{Title: "Check", Value: n.Command, Short: true},
+				{Title: "Value", Value: n.Value, Short: true},
+				{Title: "State", Value: n.State, Short: true},
+			},
+			TS: n.Timestamp.Unix(),
+		}},
+	}
+
+	// The chat handles of the contacts are mentioned, they're written as the chat expects them like "<@U024BE7LH>"
+	var mentions []string
+	for _, contact := range n.Contacts {
+		if contact.Chat != "" {
+			mentions = append(mentions, contact.Chat)
+		}
+	}
+	m.Text = strings.Join(mentions, " ")
+
+	if c.Config.Token == "" {
+		return c.post(c.Config.WebhookURL, m, nil)
+	}
+
+	// Everything after the first firing message is posted in its thread until the alert resolves,
+	// digests aren't about a single alert so they're never threaded
+	threaded := n.AlertID != "" && n.State != DigestStateDigest
+	key := string(n.AlertID) + string(n.ClientID)
+	if threaded {
+		chatThreadsRW.RLock()
+		m.ThreadTS = chatThreads[key]
+		chatThreadsRW.RUnlock()
+	}
+
+	var resp struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		TS    string `json:"ts"`
+	}
+	if err := c.post(c.Config.APIURL, m, &resp); err != nil {
+		return err
+	}
+	if !resp.OK {
+		return fmt.Errorf("chat api responded with %s", resp.Error)
+	}
+	if !threaded {
+		return nil
+	}
+
+	chatThreadsRW.Lock()
+	defer chatThreadsRW.Unlock()
+	if n.State == "resolved" {
+		delete(chatThreads, key)
+	} else if m.ThreadTS == "" {
+		chatThreads[key] = resp.TS
+	}
+	return nil
+}
+
+// post sends the message and decodes the response into result if it's not nil
+func (c Chat) post(url string, m ChatMessage, result interface{}) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling chat message")
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if c.Config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Config.Token)
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return fmt.Errorf("chat responded with %s", resp.Status)
+	}
+	if result == nil {
+		_, err = io.Copy(ioutil.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// Digest groupings, the notifications are grouped by the client, the group of the check or the type of alert
+const (
+	DigestByClient = "client"
+	DigestByGroup  = "group"
+	DigestByAlert  = "alert"
+)
+
+// DigestStateDigest is the state of a digest notification
+const DigestStateDigest = "digest"
+
+// DigestRule is the digest configuration of a service
+type DigestRule struct {
+	Window  int    `mapstructure:"window"` // In seconds, notifications within the window are sent together
+	GroupBy string `mapstructure:"group_by"`
+}
+
+// LoadDigestConfig reads the digest rules from the "digest" key in the server config, the rules
+// are per service like {"email": {"window": 300, "group_by": "client"}}
+func LoadDigestConfig() (map[string]DigestRule, error) {
+	var rules map[string]DigestRule
+	if err := viper.UnmarshalKey("digest", &rules); err != nil {
+		return nil, errors.Wrap(err, "error reading digest config")
+	}
+	for service, rule := range rules {
+		if rule.Window <= 0 {
+			return nil, fmt.Errorf("digest for %s needs a window", service)
+		}
+		switch rule.GroupBy {
+		case "":
+			rule.GroupBy = DigestByClient
+			rules[service] = rule
+		case DigestByClient, DigestByGroup, DigestByAlert:
+		default:
+			return nil, fmt.Errorf("unknown digest grouping for %s: %s", service, rule.GroupBy)
+		}
+	}
+	return rules, nil
+}
+
+// digestBucket contains the notifications of a service for a single group
+type digestBucket struct {
+	service       string
+	s             Service
+	label         string
+	started       time.Time
+	notifications []Notification
+}
+
+// Digest collects the notifications of the services that have a digest rule and sends them
+// together as a single notification when the window of the rule has passed
+type Digest struct {
+	Rules   map[string]DigestRule
+	Deliver func(service string, s Service, n Notification) // Sends the digest notification
+
+	rw      *sync.RWMutex
+	buckets map[string]*digestBucket
+	stop    chan struct{}
+}
+
+// NewDigest creates a digest with the rules
+func NewDigest(rules map[string]DigestRule, deliver func(string, Service, Notification)) *Digest {
+	return &Digest{
+		Rules:   rules,
+		Deliver: deliver,
+		rw:      new(sync.RWMutex),
+		buckets: map[string]*digestBucket{},
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start starts sending the digests in the background
+func (d *Digest) Start() {
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+				d.flush(false)
+			}
+		}
+	}()
+}
+
+// Stop sends all of the collected notifications and stops sending digests
+func (d *Digest) Stop() {
+	close(d.stop)
+	d.flush(true)
+}
+
+// Add collects the notification if the service has a digest rule, false is returned
+// if the service doesn't have one and the notification should be sent directly
+func (d *Digest) Add(service string, s Service, n Notification) bool {
+	rule, ok := d.rule(service)
+	if !ok {
+		return false
+	}
+
+	var group, label string
+	switch rule.GroupBy {
+	case DigestByClient:
+		group, label = n.ClientID.Hex(), n.ClientIP
+	case DigestByGroup:
+		group, label = n.Group, n.Group
+	case DigestByAlert:
+		group, label = n.Provider, n.Provider
+	}
+
+	d.rw.Lock()
+	defer d.rw.Unlock()
+	key := service + "|" + group
+	b, ok := d.buckets[key]
+	if !ok {
+		b = &digestBucket{service: service, s: s, label: label, started: time.Now()}
+		d.buckets[key] = b
+	}
+	b.notifications = append(b.notifications, n)
+	return true
+}
+
+// rule returns the digest rule of the service, a rule for the type of service like "email"
+// is used for all services of that type like "email:ops"
+func (d *Digest) rule(service string) (DigestRule, bool) {
+	if rule, ok := d.Rules[service]; ok {
+		return rule, true
+	}
+	if i := strings.Index(service, ":"); i >= 0 {
+		rule, ok := d.Rules[service[:i]]
+		return rule, ok
+	}
+	return DigestRule{}, false
+}
+
+// flush sends the digests whose window has passed, or all of them
+func (d *Digest) flush(all bool) {
+	var due []*digestBucket
+	d.rw.Lock()
+	for key, b := range d.buckets {
+		rule, _ := d.rule(b.service)
+		if all || time.Since(b.started) >= time.Duration(rule.Window)*time.Second {
+			due = append(due, b)
+			delete(d.buckets, key)
+		}
+	}
+	d.rw.Unlock()
+
+	for _, b := range due {
+		d.Deliver(b.service, b.s, b.summary())
+	}
+}
+
+// severities orders the severities so the most severe notification decides the severity of the digest
+var severities = map[string]int{"info": 1, "warning": 2, "critical": 3}
+
+// summary creates a single notification that lists all of the notifications in the bucket
+func (b *digestBucket) summary() Notification {
+	first := b.notifications[0]
+	if len(b.notifications) == 1 {
+		return first
+	}
+
+	n := Notification{
+		Title:     fmt.Sprintf("%d alerts for %s", len(b.notifications), b.label),
+		State:     DigestStateDigest,
+		Timestamp: time.Now(),
+	}
+
+	var msg bytes.Buffer
+	seen := map[string]bool{}
+	for i, c := range b.notifications {
+		if severities[c.Severity] > severities[n.Severity] {
+			n.Severity = c.Severity
+		}
+		n.Suppressed += c.Suppressed
+		for _, contact := range c.Contacts {
+			key := recipientKey("", contact)
+			if !seen[key] {
+				seen[key] = true
+				n.Contacts = append(n.Contacts, contact)
+			}
+		}
+
+		if i > 0 {
+			msg.WriteString("\n")
+		}
+		fmt.Fprintf(&msg, "%s %s %s (%s): %s", c.Timestamp.Format("15:04:05"), c.ClientIP, c.Command, c.State, c.Message)
+	}
+	n.Message = msg.String()
+
+	// Everything that all of the notifications have in common is kept
+	if same(b.notifications, func(c Notification) string { return c.ClientID.Hex() }) {
+		n.ClientID, n.ClientIP = first.ClientID, first.ClientIP
+	}
+	if same(b.notifications, func(c Notification) string { return c.Group }) {
+		n.Group = first.Group
+	}
+	if same(b.notifications, func(c Notification) string { return c.Provider }) {
+		n.Provider = first.Provider
+	}
+	return n
+}
+
+func same(notifications []Notification, value func(Notification) string) bool {
+	for _, n := range notifications[1:] {
+		if value(n) != value(notifications[0]) {
+			return false
+		}
+	}
+	return true
+}
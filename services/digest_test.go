@@ -0,0 +1,162 @@
+package services
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// digestDelivery is a digest notification that was sent
+type digestDelivery struct {
+	service string
+	n       Notification
+}
+
+// testDigest creates a digest that collects the sent digests, ordered by service and title
+func testDigest(rules map[string]DigestRule) (*Digest, func() []digestDelivery) {
+	var delivered []digestDelivery
+	d := NewDigest(rules, func(service string, s Service, n Notification) {
+		delivered = append(delivered, digestDelivery{service: service, n: n})
+	})
+	return d, func() []digestDelivery {
+		sort.Slice(delivered, func(i, j int) bool {
+			if delivered[i].service != delivered[j].service {
+				return delivered[i].service < delivered[j].service
+			}
+			return delivered[i].n.Title < delivered[j].n.Title
+		})
+		result := delivered
+		delivered = nil
+		return result
+	}
+}
+
+func TestDigestAdd(t *testing.T) {
+	d, _ := testDigest(map[string]DigestRule{"email": {Window: 300, GroupBy: DigestByClient}})
+
+	tests := []struct {
+		service   string
+		collected bool
+	}{
+		{"email", true},
+		{"email:ops", true},
+		{"sms", false},
+		{"emails", false},
+	}
+
+	for _, test := range tests {
+		if collected := d.Add(test.service, nil, Notification{}); collected != test.collected {
+			t.Errorf("%s: collected is %t, expected %t", test.service, collected, test.collected)
+		}
+	}
+}
+
+func TestDigestGroupBy(t *testing.T) {
+	web, db := bson.NewObjectId(), bson.NewObjectId()
+	notifications := []Notification{
+		{ClientID: web, ClientIP: "10.0.0.1", Group: "web", Provider: "CPU", Severity: "warning", Message: "CPU Usage: 85"},
+		{ClientID: web, ClientIP: "10.0.0.1", Group: "web", Provider: "Memory", Severity: "critical", Message: "Memory Usage: 97"},
+		{ClientID: db, ClientIP: "10.0.0.2", Group: "web", Provider: "CPU", Severity: "warning", Message: "CPU Usage: 90"},
+	}
+
+	tests := []struct {
+		groupBy string
+		titles  []string
+	}{
+		{DigestByClient, []string{"2 alerts for 10.0.0.1", ""}},
+		{DigestByGroup, []string{"3 alerts for web"}},
+		{DigestByAlert, []string{"2 alerts for CPU", ""}},
+	}
+
+	for _, test := range tests {
+		d, delivered := testDigest(map[string]DigestRule{"email": {Window: 300, GroupBy: test.groupBy}})
+		for _, n := range notifications {
+			d.Add("email", nil, n)
+		}
+		d.flush(true)
+
+		var titles []string
+		for _, delivery := range delivered() {
+			titles = append(titles, delivery.n.Title)
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(titles)))
+		if !reflect.DeepEqual(titles, test.titles) {
+			t.Errorf("%s: sent %q, expected %q", test.groupBy, titles, test.titles)
+		}
+	}
+}
+
+func TestDigestFlush(t *testing.T) {
+	d, delivered := testDigest(map[string]DigestRule{
+		"email": {Window: 300, GroupBy: DigestByClient},
+		"chat":  {Window: 60, GroupBy: DigestByClient},
+	})
+	client := bson.NewObjectId()
+	n := Notification{ClientID: client, ClientIP: "10.0.0.1", Message: "CPU Usage: 95"}
+	d.Add("email", nil, n)
+	d.Add("chat", nil, n)
+
+	d.flush(false)
+	if sent := delivered(); len(sent) != 0 {
+		t.Fatalf("sent %+v before the windows passed", sent)
+	}
+
+	// Only the digest whose window has passed is sent
+	d.rw.Lock()
+	for _, b := range d.buckets {
+		b.started = b.started.Add(-2 * time.Minute)
+	}
+	d.rw.Unlock()
+	d.flush(false)
+	if sent := delivered(); len(sent) != 1 || sent[0].service != "chat" || sent[0].n.Message != n.Message {
+		t.Fatalf("sent %+v, expected the chat notification", sent)
+	}
+
+	// A single notification is sent as it is and the next notification starts a new window
+	d.Add("chat", nil, n)
+	d.flush(true)
+	if sent := delivered(); len(sent) != 2 {
+		t.Errorf("sent %+v, expected the email and the new chat notification", sent)
+	}
+}
+
+func TestDigestSummary(t *testing.T) {
+	client := bson.NewObjectId()
+	anna := Contact{Name: "Anna", Email: "anna@example.com"}
+	bo := Contact{Name: "Bo", Email: "bo@example.com"}
+	at := time.Date(2026, 1, 5, 12, 30, 0, 0, time.UTC)
+
+	b := &digestBucket{label: "10.0.0.1", notifications: []Notification{
+		{ClientID: client, ClientIP: "10.0.0.1", Command: "cpu", Group: "web", Provider: "CPU", State: "firing", Severity: "warning",
+			Message: "CPU Usage: 85", Timestamp: at, Contacts: []Contact{anna}, Suppressed: 1},
+		{ClientID: client, ClientIP: "10.0.0.1", Command: "memory", Group: "web", Provider: "Memory", State: "firing", Severity: "critical",
+			Message: "Memory Usage: 97", Timestamp: at.Add(time.Minute), Contacts: []Contact{anna, bo}, Suppressed: 2},
+		{ClientID: client, ClientIP: "10.0.0.1", Command: "cpu", Group: "web", Provider: "CPU", State: "resolved", Severity: "info",
+			Message: "Resolved: CPU Usage: 50", Timestamp: at.Add(2 * time.Minute)},
+	}}
+
+	n := b.summary()
+	if n.Title != "3 alerts for 10.0.0.1" || n.State != DigestStateDigest || n.Severity != "critical" || n.Suppressed != 3 {
+		t.Errorf("summary is %+v", n)
+	}
+	if n.ClientID != client || n.ClientIP != "10.0.0.1" || n.Group != "web" || n.Provider != "" {
+		t.Errorf("the common values of the summary are %+v", n)
+	}
+	if !reflect.DeepEqual(n.Contacts, []Contact{anna, bo}) {
+		t.Errorf("contacts are %v, expected %v", n.Contacts, []Contact{anna, bo})
+	}
+
+	lines := strings.Split(n.Message, "\n")
+	expected := []string{
+		"12:30:00 10.0.0.1 cpu (firing): CPU Usage: 85",
+		"12:31:00 10.0.0.1 memory (firing): Memory Usage: 97",
+		"12:32:00 10.0.0.1 cpu (resolved): Resolved: CPU Usage: 50",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("message is %q, expected %q", lines, expected)
+	}
+}
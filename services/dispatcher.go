@@ -0,0 +1,375 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Job statuses, a job is retried until it's delivered or it has failed too many times and is dead,
+// a deferred job isn't sent before its first attempt
+const (
+	JobPending   = "pending"
+	JobDeferred  = "deferred"
+	JobRetrying  = "retrying"
+	JobDelivered = "delivered"
+	JobDead      = "dead"
+)
+
+// Job is a notification that should be delivered by a single service
+type Job struct {
+	ID           string       `json:"id"`
+	Service      string       `json:"service"` // The service like "sms:oncall", used to recreate the service after a restart
+	Notification Notification `json:"notification"`
+	Status       string       `json:"status"`
+	Attempts     int          `json:"attempts"`
+	NextAttempt  time.Time    `json:"next_attempt"`
+	LastError    string       `json:"last_error,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// DispatcherConfig is the configuration of the notification queue
+type DispatcherConfig struct {
+	Dir        string        // The queue is stored in the "pending" and "dead" folders in this folder
+	Retries    int           // The amount of retries before a job is dead
+	Backoff    time.Duration // The delay before the first retry, it's doubled for every retry after that
+	MaxBackoff time.Duration
+}
+
+// Dispatcher delivers notifications through a queue stored on disk, failed deliveries are retried with
+// an exponential backoff and the jobs that failed too many times are kept in a dead letter list
+type Dispatcher struct {
+	Config   DispatcherConfig
+	Resolve  func(service string) (Service, error) // Creates a service for the jobs that were queued before a restart
+	OnStatus func(Job)                             // Called whenever the status of a job changes, from multiple goroutines
+
+	rw       *sync.RWMutex
+	jobs     map[string]*Job
+	services map[string]Service
+	inflight map[string]bool
+	wake     chan struct{}
+	stop     chan struct{}
+}
+
+// NewDispatcher creates a dispatcher and loads the jobs that were queued before
+func NewDispatcher(config DispatcherConfig, resolve func(string) (Service, error)) (*Dispatcher, error) {
+	d := &Dispatcher{
+		Config:   config,
+		Resolve:  resolve,
+		rw:       new(sync.RWMutex),
+		jobs:     map[string]*Job{},
+		services: map[string]Service{},
+		inflight: map[string]bool{},
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+
+	for _, dir := range []string{d.dir(JobPending), d.dir(JobDead)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, errors.Wrap(err, "error creating queue folder")
+		}
+	}
+
+	jobs, err := d.read(JobPending)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading queue")
+	}
+	for i := range jobs {
+		d.jobs[jobs[i].ID] = &jobs[i]
+	}
+	return d, nil
+}
+
+// Start starts delivering the queued jobs in the background
+func (d *Dispatcher) Start() {
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+			case <-d.wake:
+			}
+			d.dispatch()
+		}
+	}()
+}
+
+// Stop stops delivering jobs, the jobs that are left are delivered after the next start
+func (d *Dispatcher) Stop() {
+	close(d.stop)
+}
+
+// Enqueue queues the notification for the service, the service is the name the service was created from
+func (d *Dispatcher) Enqueue(service string, s Service, n Notification) error {
+	return d.EnqueueAt(service, s, n, time.Now())
+}
+
+// EnqueueAt queues the notification for the service, it isn't sent before the specific time
+func (d *Dispatcher) EnqueueAt(service string, s Service, n Notification, at time.Time) error {
+	now := time.Now()
+	job := &Job{
+		ID:           bson.NewObjectId().Hex(),
+		Service:      service,
+		Notification: n,
+		Status:       JobPending,
+		NextAttempt:  at,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if at.After(now) {
+		job.Status = JobDeferred
+	}
+	if err := d.write(JobPending, *job); err != nil {
+		return errors.Wrap(err, "error queueing notification")
+	}
+
+	d.rw.Lock()
+	d.jobs[job.ID] = job
+	d.services[service] = s
+	d.rw.Unlock()
+
+	d.status(*job)
+	d.signal()
+	return nil
+}
+
+// Jobs returns all of the jobs that haven't been delivered yet, ordered by when they were queued
+func (d *Dispatcher) Jobs() []Job {
+	d.rw.RLock()
+	defer d.rw.RUnlock()
+	jobs := make([]Job, 0, len(d.jobs))
+	for _, job := range d.jobs {
+		jobs = append(jobs, *job)
+	}
+	sortJobs(jobs)
+	return jobs
+}
+
+// DeadLetters returns all of the jobs that failed too many times, ordered by when they were queued
+func (d *Dispatcher) DeadLetters() ([]Job, error) {
+	return d.read(JobDead)
+}
+
+// RetryDeadLetter queues a dead job again with all of its retries
+func (d *Dispatcher) RetryDeadLetter(id string) error {
+	job, err := d.readJob(JobDead, id)
+	if err != nil {
+		return err
+	}
+
+	job.Status = JobPending
+	job.Attempts = 0
+	job.NextAttempt = time.Now()
+	job.UpdatedAt = time.Now()
+	if err = d.write(JobPending, job); err != nil {
+		return err
+	}
+	if err = os.Remove(d.path(JobDead, id)); err != nil {
+		return err
+	}
+
+	d.rw.Lock()
+	d.jobs[job.ID] = &job
+	d.rw.Unlock()
+
+	d.status(job)
+	d.signal()
+	return nil
+}
+
+// DeleteDeadLetter removes a dead job
+func (d *Dispatcher) DeleteDeadLetter(id string) error {
+	if !bson.IsObjectIdHex(id) {
+		return fmt.Errorf("invalid job id: %s", id)
+	}
+	return os.Remove(d.path(JobDead, id))
+}
+
+// dispatch delivers every job that is due and isn't already being delivered
+func (d *Dispatcher) dispatch() {
+	now := time.Now()
+	d.rw.Lock()
+	defer d.rw.Unlock()
+	for id, job := range d.jobs {
+		if d.inflight[id] || job.NextAttempt.After(now) {
+			continue
+		}
+		d.inflight[id] = true
+		go d.deliver(*job)
+	}
+}
+
+// deliver sends a job and updates it depending on the result
+func (d *Dispatcher) deliver(job Job) {
+	defer func() {
+		d.rw.Lock()
+		delete(d.inflight, job.ID)
+		d.rw.Unlock()
+	}()
+
+	s, err := d.service(job.Service)
+	if err == nil {
+		err = s.Send(job.Notification)
+	}
+
+	job.Attempts++
+	job.UpdatedAt = time.Now()
+	if err == nil {
+		job.Status = JobDelivered
+		job.LastError = ""
+		d.remove(job.ID)
+		if rerr := os.Remove(d.path(JobPending, job.ID)); rerr != nil && !os.IsNotExist(rerr) {
+			job.LastError = rerr.Error()
+		}
+		d.status(job)
+		return
+	}
+
+	job.LastError = err.Error()
+	if job.Attempts > d.Config.Retries {
+		job.Status = JobDead
+		d.remove(job.ID)
+		if werr := d.write(JobDead, job); werr == nil {
+			os.Remove(d.path(JobPending, job.ID))
+		}
+		d.status(job)
+		return
+	}
+
+	job.Status = JobRetrying
+	job.NextAttempt = time.Now().Add(d.backoff(job.Attempts))
+	d.write(JobPending, job)
+	d.rw.Lock()
+	if _, ok := d.jobs[job.ID]; ok {
+		d.jobs[job.ID] = &job
+	}
+	d.rw.Unlock()
+	d.status(job)
+}
+
+// backoff returns the delay before the next attempt, the delay doubles for every failed attempt
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.Config.Backoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if d.Config.MaxBackoff > 0 && delay >= d.Config.MaxBackoff {
+			return d.Config.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// service returns the service for a job, services that aren't cached are created again
+func (d *Dispatcher) service(name string) (Service, error) {
+	d.rw.RLock()
+	s, ok := d.services[name]
+	d.rw.RUnlock()
+	if ok {
+		return s, nil
+	}
+	if d.Resolve == nil {
+		return nil, fmt.Errorf("unknown service: %s", name)
+	}
+
+	s, err := d.Resolve(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating service %s", name)
+	}
+	d.rw.Lock()
+	d.services[name] = s
+	d.rw.Unlock()
+	return s, nil
+}
+
+func (d *Dispatcher) remove(id string) {
+	d.rw.Lock()
+	defer d.rw.Unlock()
+	delete(d.jobs, id)
+}
+
+func (d *Dispatcher) status(job Job) {
+	if d.OnStatus != nil {
+		d.OnStatus(job)
+	}
+}
+
+func (d *Dispatcher) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) dir(status string) string {
+	return filepath.Join(d.Config.Dir, status)
+}
+
+func (d *Dispatcher) path(status, id string) string {
+	return filepath.Join(d.dir(status), id+".json")
+}
+
+// write stores the job in a temporary file first so a crash never leaves a partial job behind
+func (d *Dispatcher) write(status string, job Job) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	tmp := d.path(status, job.ID) + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.path(status, job.ID))
+}
+
+func (d *Dispatcher) readJob(status, id string) (Job, error) {
+	var job Job
+	if !bson.IsObjectIdHex(id) {
+		return job, fmt.Errorf("invalid job id: %s", id)
+	}
+	b, err := ioutil.ReadFile(d.path(status, id))
+	if err != nil {
+		return job, err
+	}
+	return job, json.Unmarshal(b, &job)
+}
+
+// read returns all of the jobs with the status, ordered by when they were queued
+func (d *Dispatcher) read(status string) ([]Job, error) {
+	files, err := ioutil.ReadDir(d.dir(status))
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []Job
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		job, err := d.readJob(status, strings.TrimSuffix(f.Name(), ".json"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading job %s", f.Name())
+		}
+		jobs = append(jobs, job)
+	}
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+func sortJobs(jobs []Job) {
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+}
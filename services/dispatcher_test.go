@@ -0,0 +1,209 @@
+package services
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// failingService fails the first fail sends and records the notifications it delivered
+type failingService struct {
+	mu   sync.Mutex
+	fail int
+	sent []Notification
+}
+
+func (s *failingService) Name() string {
+	return "failing"
+}
+
+func (s *failingService) Send(n Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail > 0 {
+		s.fail--
+		return errors.New("service unavailable")
+	}
+	s.sent = append(s.sent, n)
+	return nil
+}
+
+// testDispatcher creates a dispatcher with its queue in a temporary folder
+func testDispatcher(t *testing.T, retries int) (*Dispatcher, func()) {
+	dir, err := ioutil.TempDir("", "dispatcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewDispatcher(DispatcherConfig{Dir: dir, Retries: retries, Backoff: time.Second, MaxBackoff: time.Minute}, nil)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return d, func() { os.RemoveAll(dir) }
+}
+
+// deliverAll delivers every queued job once, without waiting for the backoff
+func deliverAll(d *Dispatcher) {
+	for _, job := range d.Jobs() {
+		d.deliver(job)
+	}
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	d := &Dispatcher{Config: DispatcherConfig{Backoff: 5 * time.Second, MaxBackoff: time.Minute}}
+	tests := []struct {
+		attempts int
+		backoff  time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{4, 40 * time.Second},
+		{5, time.Minute},
+		{30, time.Minute},
+	}
+
+	for _, test := range tests {
+		if backoff := d.backoff(test.attempts); backoff != test.backoff {
+			t.Errorf("attempt %d: backoff is %s, expected %s", test.attempts, backoff, test.backoff)
+		}
+	}
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	d, cleanup := testDispatcher(t, 3)
+	defer cleanup()
+
+	var statuses []string
+	d.OnStatus = func(job Job) {
+		statuses = append(statuses, job.Status)
+	}
+
+	s := &failingService{fail: 2}
+	if err := d.Enqueue("failing", s, Notification{Message: "CPU Usage: 95"}); err != nil {
+		t.Fatal(err)
+	}
+
+	deliverAll(d)
+	jobs := d.Jobs()
+	if len(jobs) != 1 || jobs[0].Status != JobRetrying || jobs[0].Attempts != 1 || jobs[0].LastError != "service unavailable" {
+		t.Fatalf("after the first attempt the jobs are %+v", jobs)
+	}
+	if wait := time.Until(jobs[0].NextAttempt); wait <= 0 || wait > time.Second {
+		t.Errorf("the next attempt is in %s, expected the first backoff", wait)
+	}
+
+	deliverAll(d)
+	deliverAll(d)
+	if jobs := d.Jobs(); len(jobs) != 0 {
+		t.Errorf("the delivered job is still queued: %+v", jobs)
+	}
+	if len(s.sent) != 1 || s.sent[0].Message != "CPU Usage: 95" {
+		t.Errorf("sent %+v, expected the notification once", s.sent)
+	}
+
+	expected := []string{JobPending, JobRetrying, JobRetrying, JobDelivered}
+	if len(statuses) != len(expected) {
+		t.Fatalf("statuses are %v, expected %v", statuses, expected)
+	}
+	for i := range expected {
+		if statuses[i] != expected[i] {
+			t.Errorf("statuses are %v, expected %v", statuses, expected)
+			break
+		}
+	}
+}
+
+func TestDispatcherDeadLetter(t *testing.T) {
+	d, cleanup := testDispatcher(t, 1)
+	defer cleanup()
+
+	s := &failingService{fail: 3}
+	if err := d.Enqueue("failing", s, Notification{Message: "CPU Usage: 95"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first attempt and a single retry
+	deliverAll(d)
+	deliverAll(d)
+	if jobs := d.Jobs(); len(jobs) != 0 {
+		t.Fatalf("the dead job is still queued: %+v", jobs)
+	}
+	dead, err := d.DeadLetters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].Status != JobDead || dead[0].Attempts != 2 {
+		t.Fatalf("dead letters are %+v", dead)
+	}
+
+	// A retried dead letter gets all of its retries back
+	if err = d.RetryDeadLetter(dead[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	jobs := d.Jobs()
+	if len(jobs) != 1 || jobs[0].Status != JobPending || jobs[0].Attempts != 0 {
+		t.Fatalf("after the retry the jobs are %+v", jobs)
+	}
+	if dead, _ = d.DeadLetters(); len(dead) != 0 {
+		t.Errorf("the retried job is still a dead letter: %+v", dead)
+	}
+
+	deliverAll(d)
+	deliverAll(d)
+	if len(s.sent) != 1 {
+		t.Errorf("sent %d notifications, expected 1", len(s.sent))
+	}
+
+	if err = d.DeleteDeadLetter("../pending/x"); err == nil {
+		t.Error("expected an error for an invalid job id")
+	}
+}
+
+func TestDispatcherPersistence(t *testing.T) {
+	d, cleanup := testDispatcher(t, 3)
+	defer cleanup()
+
+	later := time.Now().Add(time.Hour)
+	if err := d.Enqueue("sms:oncall", &failingService{}, Notification{Message: "now"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.EnqueueAt("email", &failingService{}, Notification{Message: "later"}, later); err != nil {
+		t.Fatal(err)
+	}
+
+	// The queue is read again after a restart and the services are created from their names
+	s := &failingService{}
+	var resolved []string
+	restarted, err := NewDispatcher(d.Config, func(name string) (Service, error) {
+		resolved = append(resolved, name)
+		return s, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jobs := restarted.Jobs()
+	if len(jobs) != 2 || jobs[0].Notification.Message != "now" || jobs[1].Status != JobDeferred || !jobs[1].NextAttempt.Equal(later) {
+		t.Fatalf("the restarted queue is %+v", jobs)
+	}
+
+	// Only the job that is due is delivered
+	restarted.dispatch()
+	for i := 0; i < 100 && len(restarted.Jobs()) > 1; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	jobs = restarted.Jobs()
+	if len(jobs) != 1 || jobs[0].Notification.Message != "later" {
+		t.Fatalf("after dispatching the queue is %+v", jobs)
+	}
+	if len(resolved) != 1 || resolved[0] != "sms:oncall" {
+		t.Errorf("resolved the services %v, expected sms:oncall", resolved)
+	}
+	if _, err := os.Stat(restarted.path(JobPending, jobs[0].ID)); err != nil {
+		t.Errorf("the deferred job isn't stored: %v", err)
+	}
+}
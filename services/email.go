@@ -0,0 +1,216 @@
+package services
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// Email TLS modes, starttls upgrades a plain connection while implicit connects with TLS directly
+const (
+	EmailTLSNone     = "none"
+	EmailTLSStartTLS = "starttls"
+	EmailTLSImplicit = "implicit"
+)
+
+// DefaultEmailPort is the SMTP submission port used when the config doesn't set one
+const DefaultEmailPort = 587
+
+// EmailConfig is the SMTP configuration read from the "email" key in the server config
+type EmailConfig struct {
+	Host               string   `mapstructure:"host"`
+	Port               int      `mapstructure:"port"`
+	TLS                string   `mapstructure:"tls"`
+	InsecureSkipVerify bool     `mapstructure:"insecure_skip_verify"`
+	Username           string   `mapstructure:"username"`
+	Password           string   `mapstructure:"password"`
+	From               string   `mapstructure:"from"`
+	To                 []string `mapstructure:"to"`
+	Timeout            int      `mapstructure:"timeout"` // In seconds
+}
+
+// LoadEmailConfig reads the email configuration from the server config
+func LoadEmailConfig() (EmailConfig, error) {
+	var c EmailConfig
+	if err := viper.UnmarshalKey("email", &c); err != nil {
+		return c, errors.Wrap(err, "error reading email config")
+	}
+	if c.Host == "" || c.From == "" {
+		return c, errors.New("email config needs a host and a from address")
+	}
+	// The defaults in the server config aren't used for the values missing from a partial "email" block
+	if c.Port == 0 {
+		c.Port = DefaultEmailPort
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	switch c.TLS {
+	case "":
+		c.TLS = EmailTLSStartTLS
+	case EmailTLSNone, EmailTLSStartTLS, EmailTLSImplicit:
+	default:
+		return c, fmt.Errorf("unknown email tls mode: %s", c.TLS)
+	}
+	return c, nil
+}
+
+type Email struct {
+	Config EmailConfig
+}
+
+func (Email) Name() string {
+	return "email"
+}
+
+// Send sends the notification to the email addresses of the contacts, the configured
+// recipients are used when none of the contacts has an email address
+func (e Email) Send(n Notification) error {
+	var to []string
+	for _, c := range n.Contacts {
+		if c.Email != "" {
+			to = append(to, c.Email)
+		}
+	}
+	if len(to) == 0 {
+		return e.SendMail(n.Title, n.Message)
+	}
+	return e.sendMail(to, n.Title, n.Message)
+}
+
+// SendMail sends the message as both a text and a HTML body to all recipients
+func (e Email) SendMail(subject, msg string) error {
+	return e.sendMail(e.Config.To, subject, msg)
+}
+
+func (e Email) sendMail(to []string, subject, msg string) error {
+	if len(to) == 0 {
+		return errors.New("no email recipients")
+	}
+
+	body, contentType, err := e.body(subject, msg)
+	if err != nil {
+		return errors.Wrap(err, "error creating email body")
+	}
+
+	var b bytes.Buffer
+	b.WriteString("From: " + e.Config.From + "\r\n")
+	b.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: " + contentType + "\r\n\r\n")
+	b.Write(body)
+
+	c, err := e.dial()
+	if err != nil {
+		return errors.Wrap(err, "error connecting to smtp server")
+	}
+	defer c.Close()
+
+	if e.Config.Username != "" {
+		if err = c.Auth(smtp.PlainAuth("", e.Config.Username, e.Config.Password, e.Config.Host)); err != nil {
+			return errors.Wrap(err, "error authenticating")
+		}
+	}
+	if err = c.Mail(e.Config.From); err != nil {
+		return errors.Wrap(err, "error setting sender")
+	}
+	for _, rcpt := range to {
+		if err = c.Rcpt(rcpt); err != nil {
+			return errors.Wrapf(err, "error adding recipient %s", rcpt)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return errors.Wrap(err, "error starting data")
+	}
+	if _, err = w.Write(b.Bytes()); err != nil {
+		return errors.Wrap(err, "error writing data")
+	}
+	if err = w.Close(); err != nil {
+		return errors.Wrap(err, "error sending data")
+	}
+	return c.Quit()
+}
+
+// dial connects to the SMTP server with the configured TLS mode
+func (e Email) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(e.Config.Host, strconv.Itoa(e.Config.Port))
+	dialer := &net.Dialer{Timeout: time.Duration(e.Config.Timeout) * time.Second}
+	tlsConfig := &tls.Config{ServerName: e.Config.Host, InsecureSkipVerify: e.Config.InsecureSkipVerify}
+
+	var conn net.Conn
+	var err error
+	if e.Config.TLS == EmailTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := smtp.NewClient(conn, e.Config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if e.Config.TLS == EmailTLSStartTLS {
+		if err = c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// body creates a multipart body with a text and a HTML part and returns it together with its content type
+func (e Email) body(title, msg string) ([]byte, string, error) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+
+	text := fmt.Sprintf("%s\r\n\r\n%s\r\n", title, msg)
+	htm := fmt.Sprintf("<html><body><h2>%s</h2><p>%s</p></body></html>\r\n",
+		html.EscapeString(title), strings.Replace(html.EscapeString(msg), "\n", "<br>", -1))
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", htm},
+	} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err = qp.Write([]byte(part.content)); err != nil {
+			return nil, "", err
+		}
+		if err = qp.Close(); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return b.Bytes(), "multipart/alternative; boundary=" + w.Boundary(), nil
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"net"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// smtpMessage is a message received by the SMTP stand-in
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpServer starts a minimal SMTP server that accepts every message without authentication
+// or TLS, the received messages are sent to the returned channel
+func smtpServer(t *testing.T) (string, int, <-chan smtpMessage) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := make(chan smtpMessage, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		tc := textproto.NewConn(conn)
+		tc.PrintfLine("220 localhost ESMTP")
+		var m smtpMessage
+		for {
+			line, err := tc.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch cmd {
+			case "EHLO", "HELO":
+				tc.PrintfLine("250 localhost")
+			case "MAIL":
+				m.from = smtpAddress(line)
+				tc.PrintfLine("250 OK")
+			case "RCPT":
+				m.to = append(m.to, smtpAddress(line))
+				tc.PrintfLine("250 OK")
+			case "DATA":
+				tc.PrintfLine("354 Go ahead")
+				b, err := tc.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				m.data = string(b)
+				tc.PrintfLine("250 OK")
+				messages <- m
+			case "QUIT":
+				tc.PrintfLine("221 Bye")
+				return
+			default:
+				tc.PrintfLine("502 Not implemented")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return host, p, messages
+}
+
+// smtpAddress returns the address of a "MAIL FROM:<address>" or "RCPT TO:<address>" line
+func smtpAddress(line string) string {
+	start, end := strings.Index(line, "<"), strings.Index(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+func TestEmailSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		contacts []Contact
+		to       []string
+	}{
+		{"configured recipients", nil, []string{"ops@example.com"}},
+		{"contacts", []Contact{
+			{Name: "Anna", Email: "anna@example.com"},
+			{Name: "Bo", Phone: "46700000000"},
+			{Name: "Cia", Email: "cia@example.com"},
+		}, []string{"anna@example.com", "cia@example.com"}},
+		{"contacts without email", []Contact{{Name: "Bo", Phone: "46700000000"}}, []string{"ops@example.com"}},
+	}
+
+	for _, test := range tests {
+		host, port, messages := smtpServer(t)
+		e := NewServiceEmail(EmailConfig{
+			Host:    host,
+			Port:    port,
+			TLS:     EmailTLSNone,
+			From:    "keiwi@example.com",
+			To:      []string{"ops@example.com"},
+			Timeout: 5,
+		})
+
+		err := e.Send(Notification{Title: "CPU alert", Message: "CPU Usage: 95", Contacts: test.contacts})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		m := <-messages
+		if m.from != "keiwi@example.com" {
+			t.Errorf("%s: sender is %q", test.name, m.from)
+		}
+		if !reflect.DeepEqual(m.to, test.to) {
+			t.Errorf("%s: recipients are %v, expected %v", test.name, m.to, test.to)
+		}
+		if !strings.Contains(m.data, "Subject: CPU alert") || !strings.Contains(m.data, "CPU Usage: 95") {
+			t.Errorf("%s: message is missing the subject or body:\n%s", test.name, m.data)
+		}
+		if !strings.Contains(m.data, "To: "+strings.Join(test.to, ", ")) {
+			t.Errorf("%s: message has the wrong to header:\n%s", test.name, m.data)
+		}
+	}
+}
+
+func TestEmailSendWithoutRecipients(t *testing.T) {
+	e := NewServiceEmail(EmailConfig{Host: "127.0.0.1", Port: 25, TLS: EmailTLSNone, From: "keiwi@example.com"})
+	if err := e.Send(Notification{Title: "CPU alert"}); err == nil {
+		t.Error("expected an error when there are no recipients")
+	}
+}
+
+// readConfig replaces the server config with the json config
+func readConfig(t *testing.T, config string) {
+	viper.Reset()
+	viper.SetConfigType("json")
+	if err := viper.ReadConfig(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadEmailConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		email  EmailConfig
+		err    bool
+	}{
+		{
+			name:   "partial config",
+			config: `{"email":{"host":"smtp.example.com","from":"keiwi@example.com"}}`,
+			email:  EmailConfig{Host: "smtp.example.com", From: "keiwi@example.com", Port: DefaultEmailPort, TLS: EmailTLSStartTLS, Timeout: DefaultTimeout},
+		},
+		{
+			name:   "full config",
+			config: `{"email":{"host":"smtp.example.com","port":465,"tls":"implicit","from":"keiwi@example.com","to":["ops@example.com"],"timeout":30}}`,
+			email:  EmailConfig{Host: "smtp.example.com", Port: 465, TLS: EmailTLSImplicit, From: "keiwi@example.com", To: []string{"ops@example.com"}, Timeout: 30},
+		},
+		{name: "without host", config: `{"email":{"from":"keiwi@example.com"}}`, err: true},
+		{name: "unknown tls mode", config: `{"email":{"host":"smtp.example.com","from":"keiwi@example.com","tls":"ssl"}}`, err: true},
+	}
+
+	for _, test := range tests {
+		readConfig(t, test.config)
+		c, err := LoadEmailConfig()
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(c, test.email) {
+			t.Errorf("%s: got %+v, expected %+v", test.name, c, test.email)
+		}
+	}
+}
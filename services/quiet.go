@@ -0,0 +1,288 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// Quiet hour actions, a notification is either dropped, sent when the quiet hours end or sent by another service
+const (
+	QuietSuppress = "suppress"
+	QuietDefer    = "defer"
+	QuietReroute  = "reroute"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// QuietRule is a time window where the notifications of services or contacts are held back, it's read
+// from the "quiet_hours" key in the server config like {"services": ["sms"], "days": ["mon", "tue"],
+// "start": "22:00", "end": "07:00", "timezone": "Europe/Stockholm", "action": "reroute", "reroute": "email",
+// "allow": "critical"}. A window that ends before it starts continues over midnight
+type QuietRule struct {
+	Services []string `mapstructure:"services"` // All services if empty
+	Contacts []string `mapstructure:"contacts"` // Names of the contacts, the whole notification if empty
+	Days     []string `mapstructure:"days"`     // The days the window starts on, every day if empty
+	Start    string   `mapstructure:"start"`
+	End      string   `mapstructure:"end"`
+	Timezone string   `mapstructure:"timezone"`
+	Action   string   `mapstructure:"action"`
+	Reroute  string   `mapstructure:"reroute"` // The service that gets the notifications when rerouting
+	Allow    string   `mapstructure:"allow"`   // Notifications with this severity or higher aren't held back
+
+	location *time.Location
+	days     map[time.Weekday]bool
+	start    int // In minutes after midnight
+	end      int
+}
+
+// LoadQuietRules reads the quiet hours from the server config
+func LoadQuietRules() ([]QuietRule, error) {
+	var rules []QuietRule
+	if err := viper.UnmarshalKey("quiet_hours", &rules); err != nil {
+		return nil, errors.Wrap(err, "error reading quiet hours")
+	}
+	for i := range rules {
+		if err := rules[i].parse(); err != nil {
+			return nil, errors.Wrapf(err, "error in quiet hours rule %d", i+1)
+		}
+	}
+	return rules, nil
+}
+
+// parse validates the rule and parses its days, times and timezone
+func (r *QuietRule) parse() error {
+	var err error
+	if r.location, err = time.LoadLocation(r.Timezone); err != nil {
+		return err
+	}
+	if r.start, err = parseClock(r.Start); err != nil {
+		return err
+	}
+	if r.end, err = parseClock(r.End); err != nil {
+		return err
+	}
+
+	r.days = map[time.Weekday]bool{}
+	for _, d := range r.Days {
+		// Both "mon" and "monday" are accepted
+		name := strings.ToLower(strings.TrimSpace(d))
+		if len(name) > 3 {
+			name = name[:3]
+		}
+		day, ok := weekdays[name]
+		if !ok {
+			return fmt.Errorf("unknown day: %s", d)
+		}
+		r.days[day] = true
+	}
+
+	switch r.Action {
+	case "":
+		r.Action = QuietSuppress
+	case QuietSuppress:
+	case QuietDefer:
+		// A window that never closes would hold back the notifications forever
+		if r.start == r.end && (len(r.days) == 0 || len(r.days) == len(weekdays)) {
+			return errors.New("a deferring rule can't be active all the time, use suppress instead")
+		}
+	case QuietReroute:
+		if r.Reroute == "" {
+			return errors.New("a rerouting rule needs a service to reroute to")
+		}
+	default:
+		return fmt.Errorf("unknown action: %s", r.Action)
+	}
+	if _, ok := severities[r.Allow]; r.Allow != "" && !ok {
+		return fmt.Errorf("unknown severity: %s", r.Allow)
+	}
+	return nil
+}
+
+// parseClock parses a time like "22:00" into minutes after midnight
+func parseClock(s string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("the time: %s should be written as \"hh:mm\"", s)
+	}
+	h, err := strconv.Atoi(parts[0])
+	if err != nil || h < 0 || h > 24 {
+		return 0, fmt.Errorf("invalid hour in: %s", s)
+	}
+	m, err := strconv.Atoi(parts[1])
+	if err != nil || m < 0 || m > 59 || h*60+m > 24*60 {
+		return 0, fmt.Errorf("invalid minute in: %s", s)
+	}
+	return h*60 + m, nil
+}
+
+// Active returns whether the window is active at the specific time
+func (r QuietRule) Active(t time.Time) bool {
+	t = t.In(r.location)
+	minute := t.Hour()*60 + t.Minute()
+	day := func(d time.Weekday) bool {
+		return len(r.days) == 0 || r.days[d]
+	}
+
+	switch {
+	case r.start == r.end:
+		return day(t.Weekday())
+	case r.start < r.end:
+		return day(t.Weekday()) && minute >= r.start && minute < r.end
+	}
+	// The window continues over midnight into the next day
+	return (day(t.Weekday()) && minute >= r.start) || (day(t.AddDate(0, 0, -1).Weekday()) && minute < r.end)
+}
+
+// Opens returns when notifications can be sent again after the window that is active at the specific time,
+// false is returned if the window never closes
+func (r QuietRule) Opens(t time.Time) (time.Time, bool) {
+	next := t.Truncate(time.Minute)
+	for i := 0; i < 8*24*60; i++ {
+		next = next.Add(time.Minute)
+		if !r.Active(next) {
+			return next, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// applies returns whether the rule holds back the notification for the service
+func (r QuietRule) applies(service string, n Notification, t time.Time) bool {
+	if r.Allow != "" && severities[n.Severity] >= severities[r.Allow] {
+		return false
+	}
+	if len(r.Services) > 0 {
+		name := service
+		if i := strings.Index(service, ":"); i >= 0 {
+			name = service[:i]
+		}
+		found := false
+		for _, s := range r.Services {
+			if s == service || s == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return r.Active(t)
+}
+
+// matches returns whether the rule is for the contact
+func (r QuietRule) matches(c Contact) bool {
+	for _, name := range r.Contacts {
+		if strings.EqualFold(name, c.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// QuietDelivery is a notification that should be sent by a service, possibly at a later time
+type QuietDelivery struct {
+	Service      string
+	Notification Notification
+	At           time.Time // Zero if the notification should be sent directly
+}
+
+// QuietHours holds back the notifications of the services and contacts that are in their quiet hours
+type QuietHours struct {
+	Rules []QuietRule
+}
+
+// NewQuietHours creates quiet hours with the rules
+func NewQuietHours(rules []QuietRule) *QuietHours {
+	return &QuietHours{Rules: rules}
+}
+
+// Apply returns how the notification for the service should be delivered, the notification is split
+// when only some of its contacts are in their quiet hours. Nothing is returned for suppressed notifications.
+// Rerouted notifications follow the quiet hours of the service they're rerouted to
+func (q *QuietHours) Apply(service string, n Notification, t time.Time) []QuietDelivery {
+	return q.apply(service, n, t, map[string]bool{})
+}
+
+// apply returns the deliveries for the service, rerouted is the services that the notification has been
+// rerouted from so a notification that is rerouted back to one of them is suppressed
+func (q *QuietHours) apply(service string, n Notification, t time.Time, rerouted map[string]bool) []QuietDelivery {
+	var deliveries []QuietDelivery
+	for _, d := range q.rules(service, n, t) {
+		if d.Service == service {
+			deliveries = append(deliveries, d)
+			continue
+		}
+		if rerouted[d.Service] {
+			continue
+		}
+
+		next := map[string]bool{service: true}
+		for s := range rerouted {
+			next[s] = true
+		}
+		deliveries = append(deliveries, q.apply(d.Service, d.Notification, t, next)...)
+	}
+	return deliveries
+}
+
+// rules returns the deliveries for the service from the rules that apply to it
+func (q *QuietHours) rules(service string, n Notification, t time.Time) []QuietDelivery {
+	for _, r := range q.Rules {
+		if len(r.Contacts) == 0 && r.applies(service, n, t) {
+			return r.hold(service, n, t)
+		}
+	}
+
+	var deliveries []QuietDelivery
+	for _, r := range q.Rules {
+		if len(r.Contacts) == 0 || len(n.Contacts) == 0 || !r.applies(service, n, t) {
+			continue
+		}
+
+		var quiet, rest []Contact
+		for _, c := range n.Contacts {
+			if r.matches(c) {
+				quiet = append(quiet, c)
+			} else {
+				rest = append(rest, c)
+			}
+		}
+		if len(quiet) == 0 {
+			continue
+		}
+
+		held := n
+		held.Contacts = quiet
+		deliveries = append(deliveries, r.hold(service, held, t)...)
+		n.Contacts = rest
+		if len(rest) == 0 {
+			return deliveries
+		}
+	}
+	return append(deliveries, QuietDelivery{Service: service, Notification: n})
+}
+
+// hold returns the deliveries for a notification that the rule holds back
+func (r QuietRule) hold(service string, n Notification, t time.Time) []QuietDelivery {
+	switch r.Action {
+	case QuietDefer:
+		at, ok := r.Opens(t)
+		if !ok {
+			// The window never closes so the notification is suppressed
+			return nil
+		}
+		return []QuietDelivery{{Service: service, Notification: n, At: at}}
+	case QuietReroute:
+		return []QuietDelivery{{Service: r.Reroute, Notification: n}}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// quietRule parses the rule and fails the test if it's invalid
+func quietRule(t *testing.T, r QuietRule) QuietRule {
+	if err := r.parse(); err != nil {
+		t.Fatalf("%+v: unexpected error: %v", r, err)
+	}
+	return r
+}
+
+func TestQuietRuleParse(t *testing.T) {
+	tests := []struct {
+		rule QuietRule
+		err  bool
+	}{
+		{rule: QuietRule{Start: "22:00", End: "07:00"}},
+		{rule: QuietRule{Start: "0:00", End: "24:00", Days: []string{"Saturday", "sun"}, Action: QuietDefer}},
+		{rule: QuietRule{Start: "22:00", End: "07:00", Timezone: "Europe/Stockholm", Action: QuietReroute, Reroute: "email"}},
+		{rule: QuietRule{Start: "22:00", End: "07:00", Allow: "critical"}},
+		{rule: QuietRule{Start: "22", End: "07:00"}, err: true},
+		{rule: QuietRule{Start: "25:00", End: "07:00"}, err: true},
+		{rule: QuietRule{Start: "22:60", End: "07:00"}, err: true},
+		{rule: QuietRule{Start: "24:01", End: "07:00"}, err: true},
+		{rule: QuietRule{Start: "22:00", End: "07:00", Days: []string{"someday"}}, err: true},
+		{rule: QuietRule{Start: "22:00", End: "07:00", Timezone: "Nowhere/City"}, err: true},
+		{rule: QuietRule{Start: "22:00", End: "07:00", Action: "ignore"}, err: true},
+		{rule: QuietRule{Start: "22:00", End: "07:00", Action: QuietReroute}, err: true},
+		{rule: QuietRule{Start: "22:00", End: "07:00", Allow: "fatal"}, err: true},
+		// A deferring rule that never ends would hold back the notifications forever
+		{rule: QuietRule{Start: "00:00", End: "00:00", Action: QuietDefer}, err: true},
+		{rule: QuietRule{Start: "08:00", End: "08:00", Days: []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}, Action: QuietDefer}, err: true},
+	}
+
+	for _, test := range tests {
+		err := test.rule.parse()
+		if test.err && err == nil {
+			t.Errorf("%+v: expected an error", test.rule)
+		} else if !test.err && err != nil {
+			t.Errorf("%+v: unexpected error: %v", test.rule, err)
+		}
+	}
+}
+
+func TestQuietRuleActive(t *testing.T) {
+	night := quietRule(t, QuietRule{Start: "22:00", End: "07:00", Days: []string{"fri"}})
+	day := quietRule(t, QuietRule{Start: "09:00", End: "17:00"})
+	weekend := quietRule(t, QuietRule{Start: "00:00", End: "00:00", Days: []string{"sat", "sun"}})
+	stockholm := quietRule(t, QuietRule{Start: "22:00", End: "07:00", Timezone: "Europe/Stockholm"})
+
+	// 2026-01-02 is a friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name   string
+		rule   QuietRule
+		t      time.Time
+		active bool
+	}{
+		{"friday night", night, at(2, 23, 0), true},
+		{"friday evening", night, at(2, 21, 59), false},
+		{"saturday morning after friday night", night, at(3, 6, 59), true},
+		{"saturday at the end", night, at(3, 7, 0), false},
+		{"saturday night", night, at(3, 23, 0), false},
+		{"friday morning", night, at(2, 6, 0), false},
+		{"day start", day, at(5, 9, 0), true},
+		{"day end", day, at(5, 17, 0), false},
+		{"saturday", weekend, at(3, 12, 0), true},
+		{"monday", weekend, at(5, 0, 0), false},
+		{"utc evening in stockholm", stockholm, at(5, 21, 30), true},
+		{"utc morning in stockholm", stockholm, at(5, 6, 30), false},
+	}
+
+	for _, test := range tests {
+		if active := test.rule.Active(test.t); active != test.active {
+			t.Errorf("%s: active is %t, expected %t", test.name, active, test.active)
+		}
+	}
+}
+
+func TestQuietRuleOpens(t *testing.T) {
+	night := quietRule(t, QuietRule{Start: "22:00", End: "07:00"})
+	opens, ok := night.Opens(time.Date(2026, 1, 5, 23, 30, 15, 0, time.UTC))
+	if expected := time.Date(2026, 1, 6, 7, 0, 0, 0, time.UTC); !ok || !opens.Equal(expected) {
+		t.Errorf("opens at %s %t, expected %s", opens, ok, expected)
+	}
+
+	always := quietRule(t, QuietRule{Start: "00:00", End: "00:00"})
+	if _, ok := always.Opens(time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)); ok {
+		t.Error("a window that never closes shouldn't open")
+	}
+}
+
+func TestQuietHoursApply(t *testing.T) {
+	now := time.Date(2026, 1, 5, 23, 0, 0, 0, time.UTC)
+	morning := time.Date(2026, 1, 6, 7, 0, 0, 0, time.UTC)
+	anna := Contact{Name: "Anna", Phone: "46700000001"}
+	bo := Contact{Name: "Bo", Phone: "46700000002"}
+	n := Notification{Severity: "warning", Contacts: []Contact{anna, bo}}
+
+	with := func(n Notification, contacts ...Contact) Notification {
+		n.Contacts = contacts
+		return n
+	}
+
+	tests := []struct {
+		name       string
+		rules      []QuietRule
+		service    string
+		n          Notification
+		deliveries []QuietDelivery
+	}{
+		{
+			name:       "no rules",
+			service:    "sms",
+			n:          n,
+			deliveries: []QuietDelivery{{Service: "sms", Notification: n}},
+		},
+		{
+			name:    "suppressed",
+			rules:   []QuietRule{{Services: []string{"sms"}, Start: "22:00", End: "07:00"}},
+			service: "sms:oncall",
+			n:       n,
+		},
+		{
+			name:       "other service",
+			rules:      []QuietRule{{Services: []string{"sms"}, Start: "22:00", End: "07:00"}},
+			service:    "email",
+			n:          n,
+			deliveries: []QuietDelivery{{Service: "email", Notification: n}},
+		},
+		{
+			name:       "allowed severity",
+			rules:      []QuietRule{{Start: "22:00", End: "07:00", Allow: "warning"}},
+			service:    "sms",
+			n:          n,
+			deliveries: []QuietDelivery{{Service: "sms", Notification: n}},
+		},
+		{
+			name:       "deferred",
+			rules:      []QuietRule{{Start: "22:00", End: "07:00", Action: QuietDefer}},
+			service:    "sms",
+			n:          n,
+			deliveries: []QuietDelivery{{Service: "sms", Notification: n, At: morning}},
+		},
+		{
+			name:       "rerouted",
+			rules:      []QuietRule{{Services: []string{"sms"}, Start: "22:00", End: "07:00", Action: QuietReroute, Reroute: "email"}},
+			service:    "sms",
+			n:          n,
+			deliveries: []QuietDelivery{{Service: "email", Notification: n}},
+		},
+		{
+			name: "rerouted to a deferring service",
+			rules: []QuietRule{
+				{Services: []string{"sms"}, Start: "22:00", End: "07:00", Action: QuietReroute, Reroute: "email"},
+				{Services: []string{"email"}, Start: "22:00", End: "07:00", Action: QuietDefer},
+			},
+			service:    "sms",
+			n:          n,
+			deliveries: []QuietDelivery{{Service: "email", Notification: n, At: morning}},
+		},
+		{
+			name: "rerouted in a loop",
+			rules: []QuietRule{
+				{Services: []string{"sms"}, Start: "22:00", End: "07:00", Action: QuietReroute, Reroute: "email"},
+				{Services: []string{"email"}, Start: "22:00", End: "07:00", Action: QuietReroute, Reroute: "sms"},
+			},
+			service: "sms",
+			n:       n,
+		},
+		{
+			name:    "quiet contact",
+			rules:   []QuietRule{{Contacts: []string{"anna"}, Start: "22:00", End: "07:00", Action: QuietDefer}},
+			service: "sms",
+			n:       n,
+			deliveries: []QuietDelivery{
+				{Service: "sms", Notification: with(n, anna), At: morning},
+				{Service: "sms", Notification: with(n, bo)},
+			},
+		},
+		{
+			name:    "all contacts quiet",
+			rules:   []QuietRule{{Contacts: []string{"Anna", "Bo"}, Start: "22:00", End: "07:00"}},
+			service: "sms",
+			n:       n,
+		},
+	}
+
+	for _, test := range tests {
+		for i := range test.rules {
+			test.rules[i] = quietRule(t, test.rules[i])
+		}
+		deliveries := NewQuietHours(test.rules).Apply(test.service, test.n, now)
+		if !reflect.DeepEqual(deliveries, test.deliveries) {
+			t.Errorf("%s: got %+v, expected %+v", test.name, deliveries, test.deliveries)
+		}
+	}
+}
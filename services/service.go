@@ -1,17 +1,78 @@
 package services
 
-import "time"
+import (
+	"net/http"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Notification contains the information about an alert that is sent to the services
+type Notification struct {
+	AlertID    bson.ObjectId
+	ClientID   bson.ObjectId
+	ClientIP   string
+	Command    string
+	Group      string
+	Provider   string
+	Title      string
+	Message    string
+	Value      string
+	Threshold  string
+	Severity   string
+	State      string
+	Timestamp  time.Time
+	Since      time.Time // When the alert started firing
+	Contacts   []Contact // The contacts of the alert, services fall back to their configured recipients if none can be reached
+	Suppressed int       // The amount of notifications the service dropped because of rate limits since the last one
+}
+
+// Contact is a person that should receive a notification, every service only uses the address it can deliver to
+type Contact struct {
+	Name  string `json:"name"`
+	Phone string `json:"phone,omitempty"`
+	Email string `json:"email,omitempty"`
+	Chat  string `json:"chat,omitempty"`
+}
+
+// DefaultTimeout is the timeout in seconds the services use when their config doesn't set one
+const DefaultTimeout = 10
 
 type Service interface {
-	Send(string, string)
+	Send(Notification) error
 	Name() string
 }
 
-func NewServiceSMS() Service {
+func NewServiceSMS(config SMSConfig) Service {
+	var recipients []Recipient
+	for _, r := range config.Recipients {
+		recipients = append(recipients, Recipient{Msisdn: r})
+	}
 	return &SMS{
-		Recipients: []Recipient{
-			Recipient{Msisdn: "46724206544"},
+		Sender:     config.Sender,
+		Recipients: recipients,
+		GW:         newGatewayAPI(config.Token, config.Base, time.Duration(config.Timeout)*time.Second),
+	}
+}
+
+func NewServiceEmail(config EmailConfig) Service {
+	return &Email{Config: config}
+}
+
+func NewServiceWebhook(config WebhookConfig) Service {
+	return &Webhook{
+		Config: config,
+		Client: &http.Client{
+			Timeout: time.Duration(config.Timeout) * time.Second,
+		},
+	}
+}
+
+func NewServiceChat(config ChatConfig) Service {
+	return &Chat{
+		Config: config,
+		Client: &http.Client{
+			Timeout: time.Duration(config.Timeout) * time.Second,
 		},
-		GW: newGatewayAPI("_qVpWmD_Q-OyvBy47KdFO1nSI9s7qxRUwimw7-Anjbw_HEMiRkMer4RudKAyhE9H", time.Second*10),
 	}
 }
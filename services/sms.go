@@ -2,11 +2,86 @@ package services
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 )
 
+// SMSBaseURL is the default SMS gateway
+const SMSBaseURL = "https://gatewayapi.com"
+
+// SMSConfig is the SMS gateway configuration read from the "sms" key in the server config, named
+// profiles are read from "sms.profiles.<name>" and inherit everything they don't set from "sms"
+type SMSConfig struct {
+	Token      string   `mapstructure:"token"`
+	Base       string   `mapstructure:"base"`
+	Sender     string   `mapstructure:"sender"`
+	Recipients []string `mapstructure:"recipients"`
+	Timeout    int      `mapstructure:"timeout"` // In seconds
+}
+
+// LoadSMSConfig reads the SMS configuration for the profile from the server config, an empty
+// profile is the default configuration
+func LoadSMSConfig(profile string) (SMSConfig, error) {
+	var c SMSConfig
+	if err := viper.UnmarshalKey("sms", &c); err != nil {
+		return c, errors.Wrap(err, "error reading sms config")
+	}
+	if profile != "" {
+		key := "sms.profiles." + profile
+		if !viper.IsSet(key) {
+			return c, fmt.Errorf("unknown sms profile: %s", profile)
+		}
+		// The profile is read on its own so a shorter recipient list doesn't keep the default
+		// recipients after it, only the values set in the profile overwrite the default configuration
+		var p SMSConfig
+		if err := viper.UnmarshalKey(key, &p); err != nil {
+			return c, errors.Wrapf(err, "error reading sms profile %s", profile)
+		}
+		c.merge(p)
+	}
+	// The defaults in the server config aren't used for the values missing from a partial "sms" block
+	if c.Base == "" {
+		c.Base = SMSBaseURL
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.Token == "" {
+		return c, errors.New("sms config needs a token")
+	}
+	return c, nil
+}
+
+// merge overwrites the configuration with the values that are set in the profile
+func (c *SMSConfig) merge(p SMSConfig) {
+	if p.Token != "" {
+		c.Token = p.Token
+	}
+	if p.Base != "" {
+		c.Base = p.Base
+	}
+	if p.Sender != "" {
+		c.Sender = p.Sender
+	}
+	if p.Recipients != nil {
+		c.Recipients = p.Recipients
+	}
+	if p.Timeout > 0 {
+		c.Timeout = p.Timeout
+	}
+}
+
+// DefaultSMSSender is the sender of the SMS when none is configured
+const DefaultSMSSender = "Keiwi"
+
 type Message struct {
 	Message    string      `json:"message"`
 	Sender     string      `json:"sender"`
@@ -18,6 +93,7 @@ type Recipient struct {
 }
 
 type SMS struct {
+	Sender     string
 	Recipients []Recipient
 	GW         *GatewayAPI
 }
@@ -26,21 +102,42 @@ func (SMS) Name() string {
 	return "sms"
 }
 
-func (s SMS) Send(title string, msg string) {
+func (s SMS) Send(n Notification) error {
+	sender := s.Sender
+	if sender == "" {
+		sender = DefaultSMSSender
+	}
+	// The configured recipients are used when none of the contacts has a phone number
+	var recipients []Recipient
+	for _, c := range n.Contacts {
+		if c.Phone != "" {
+			recipients = append(recipients, Recipient{Msisdn: strings.TrimPrefix(c.Phone, "+")})
+		}
+	}
+	if len(recipients) == 0 {
+		recipients = s.Recipients
+	}
+	if len(recipients) == 0 {
+		return errors.New("no sms recipients")
+	}
+
 	m := Message{
-		Message:    msg,
-		Sender:     title,
-		Recipients: s.Recipients,
+		Message:    n.Message,
+		Sender:     sender,
+		Recipients: recipients,
 	}
-	b, _ := json.Marshal(m)
-	s.GW.SendSMS(string(b))
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return s.GW.SendSMS(string(b))
 }
 
 /** TODO Move this into its own package */
-func newGatewayAPI(key string, timeout time.Duration) *GatewayAPI {
+func newGatewayAPI(key, base string, timeout time.Duration) *GatewayAPI {
 	return &GatewayAPI{
 		APIKey: key,
-		Base:   "https://GatewayAPI.com",
+		Base:   strings.TrimRight(base, "/"),
 		Client: &http.Client{
 			Timeout: timeout,
 		},
@@ -53,17 +150,30 @@ type GatewayAPI struct {
 	APIKey string
 }
 
-func (g GatewayAPI) SendSMS(message string) (*http.Response, error) {
-	req, err := g.createRequest("/rest/mtsms?token="+g.APIKey, message)
+// SendSMS sends the message to the gateway, an error is returned if the gateway didn't accept it
+func (g GatewayAPI) SendSMS(message string) error {
+	req, err := g.createRequest("/rest/mtsms?token="+url.QueryEscape(g.APIKey), message)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return g.Client.Do(req)
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// The gateway describes the error in the body, it's included but limited in size
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("gateway responded with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return err
 }
 
-func (g GatewayAPI) createRequest(url string, data string) (*http.Request, error) {
-	req, err := http.NewRequest("POST", g.Base+url, strings.NewReader(data))
+func (g GatewayAPI) createRequest(path string, data string) (*http.Request, error) {
+	req, err := http.NewRequest("POST", g.Base+path, strings.NewReader(data))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadSMSConfig(t *testing.T) {
+	config := `{"sms":{
+		"token":"abc",
+		"recipients":["46700000001","46700000002"],
+		"profiles":{
+			"oncall":{"recipients":["46700000003"]},
+			"eu":{"token":"def","base":"https://eu.gatewayapi.com","timeout":30}
+		}
+	}}`
+
+	tests := []struct {
+		name    string
+		config  string
+		profile string
+		sms     SMSConfig
+		err     bool
+	}{
+		{
+			name:   "partial config",
+			config: `{"sms":{"token":"abc"}}`,
+			sms:    SMSConfig{Token: "abc", Base: SMSBaseURL, Timeout: DefaultTimeout},
+		},
+		{
+			name:   "default profile",
+			config: config,
+			sms:    SMSConfig{Token: "abc", Base: SMSBaseURL, Recipients: []string{"46700000001", "46700000002"}, Timeout: DefaultTimeout},
+		},
+		{
+			name:    "profile with recipients",
+			config:  config,
+			profile: "oncall",
+			sms:     SMSConfig{Token: "abc", Base: SMSBaseURL, Recipients: []string{"46700000003"}, Timeout: DefaultTimeout},
+		},
+		{
+			name:    "profile with gateway",
+			config:  config,
+			profile: "eu",
+			sms:     SMSConfig{Token: "def", Base: "https://eu.gatewayapi.com", Recipients: []string{"46700000001", "46700000002"}, Timeout: 30},
+		},
+		{name: "unknown profile", config: config, profile: "weekend", err: true},
+		{name: "without token", config: `{"sms":{"recipients":["46700000001"]}}`, err: true},
+	}
+
+	for _, test := range tests {
+		readConfig(t, test.config)
+		c, err := LoadSMSConfig(test.profile)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(c, test.sms) {
+			t.Errorf("%s: got %+v, expected %+v", test.name, c, test.sms)
+		}
+	}
+}
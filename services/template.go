@@ -0,0 +1,126 @@
+package services
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TemplateDefault is the name used for the templates of all services or all alert types
+const TemplateDefault = "default"
+
+// The built in templates keep the title and message of the alert
+const (
+	defaultTitleTemplate = "{{.Title}}"
+	defaultBodyTemplate  = "{{.Message}}"
+)
+
+// templateFuncs are the functions available in the templates
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"date": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	"since": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return time.Since(t).Round(time.Second).String()
+	},
+}
+
+// messageTemplate is the title and body template for a service and alert type
+type messageTemplate struct {
+	title *template.Template
+	body  *template.Template
+}
+
+// Templates renders the title and message of the notifications, the templates are selected by
+// the service and the alert type and the notification is used as the data of the templates
+type Templates struct {
+	templates map[string]*template.Template
+}
+
+// LoadTemplates reads the templates from the folder, the templates are stored as
+// "<service>/<alert type>.title.tmpl" and "<service>/<alert type>.body.tmpl" where
+// "default" can be used as the service or alert type to match all of them
+func LoadTemplates(dir string) (*Templates, error) {
+	t := &Templates{templates: map[string]*template.Template{}}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading template %s", f)
+		}
+
+		service := filepath.Base(filepath.Dir(f))
+		name := strings.ToLower(service + "/" + strings.TrimSuffix(filepath.Base(f), ".tmpl"))
+		tmpl, err := template.New(name).Funcs(templateFuncs).Parse(strings.TrimRight(string(b), "\r\n"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing template %s", f)
+		}
+		t.templates[name] = tmpl
+	}
+	return t, nil
+}
+
+// Render returns the notification with the title and message from the templates for the service,
+// the service is the name the service was created from like "sms:oncall"
+func (t *Templates) Render(service string, n Notification) (Notification, error) {
+	if i := strings.Index(service, ":"); i >= 0 {
+		service = service[:i]
+	}
+
+	m := t.lookup(strings.ToLower(service), strings.ToLower(n.Provider))
+	var title, body bytes.Buffer
+	if err := m.title.Execute(&title, n); err != nil {
+		return n, errors.Wrap(err, "error rendering title")
+	}
+	if err := m.body.Execute(&body, n); err != nil {
+		return n, errors.Wrap(err, "error rendering body")
+	}
+
+	n.Title, n.Message = title.String(), body.String()
+	return n, nil
+}
+
+// lookup returns the most specific templates, the service and alert type are tried first, then only the
+// service, only the alert type, the default templates and at last the built in templates
+func (t *Templates) lookup(service, alert string) messageTemplate {
+	var m messageTemplate
+	for _, prefix := range []string{
+		service + "/" + alert,
+		service + "/" + TemplateDefault,
+		TemplateDefault + "/" + alert,
+		TemplateDefault + "/" + TemplateDefault,
+	} {
+		if m.title == nil {
+			m.title = t.templates[prefix+".title"]
+		}
+		if m.body == nil {
+			m.body = t.templates[prefix+".body"]
+		}
+	}
+	if m.title == nil {
+		m.title = defaultTemplates.title
+	}
+	if m.body == nil {
+		m.body = defaultTemplates.body
+	}
+	return m
+}
+
+var defaultTemplates = messageTemplate{
+	title: template.Must(template.New("title").Funcs(templateFuncs).Parse(defaultTitleTemplate)),
+	body:  template.Must(template.New("body").Funcs(templateFuncs).Parse(defaultBodyTemplate)),
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// templateDir writes the templates to a temporary folder, the files are named like "sms/cpu.body.tmpl"
+func templateDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestTemplatesRender(t *testing.T) {
+	dir := templateDir(t, map[string]string{
+		"sms/CPU.body.tmpl":            "{{upper .Severity}} {{.ClientIP}} cpu {{.Value}}\n",
+		"sms/default.body.tmpl":        "{{upper .Severity}} {{.ClientIP}} {{.Message}}",
+		"default/memory.title.tmpl":    "{{.Provider}} on {{.ClientIP}}",
+		"default/default.title.tmpl":   "[{{.Severity}}] {{.Title}}",
+		"email/default.body.tmpl":      "{{.Message}} since {{date \"15:04\" .Timestamp}}",
+		"chat/README.txt":              "not a template",
+		"webhook/default.title.tmpl":   "{{.Title | lower}}",
+		"webhook/default.unknown.tmpl": "{{.Title}}",
+	})
+	defer os.RemoveAll(dir)
+
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := Notification{Provider: "CPU", Title: "CPU", Message: "CPU Usage: 95", Value: "95", Severity: "critical", ClientIP: "10.0.0.1"}
+	memory := n
+	memory.Provider, memory.Title, memory.Message = "Memory", "Memory", "Memory Usage: 97"
+
+	tests := []struct {
+		name    string
+		service string
+		n       Notification
+		title   string
+		message string
+	}{
+		{"service and alert type", "sms", n, "[critical] CPU", "CRITICAL 10.0.0.1 cpu 95"},
+		{"service settings are ignored", "sms:oncall", n, "[critical] CPU", "CRITICAL 10.0.0.1 cpu 95"},
+		{"service default", "sms", memory, "Memory on 10.0.0.1", "CRITICAL 10.0.0.1 Memory Usage: 97"},
+		{"alert type default", "chat", memory, "Memory on 10.0.0.1", "Memory Usage: 97"},
+		{"default", "chat", n, "[critical] CPU", "CPU Usage: 95"},
+		{"service title before the default", "webhook", memory, "memory", "Memory Usage: 97"},
+		{"functions", "email", n, "[critical] CPU", "CPU Usage: 95 since 00:00"},
+	}
+
+	for _, test := range tests {
+		rendered, err := templates.Render(test.service, test.n)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if rendered.Title != test.title || rendered.Message != test.message {
+			t.Errorf("%s: rendered %q %q, expected %q %q", test.name, rendered.Title, rendered.Message, test.title, test.message)
+		}
+	}
+}
+
+func TestTemplatesBuiltIn(t *testing.T) {
+	templates, err := LoadTemplates(filepath.Join(os.TempDir(), "keiwi-missing-templates"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := Notification{Provider: "CPU", Title: "CPU", Message: "CPU Usage: 95"}
+	rendered, err := templates.Render("sms", n)
+	if err != nil || rendered.Title != n.Title || rendered.Message != n.Message {
+		t.Errorf("rendered %q %q %v, expected the notification as it is", rendered.Title, rendered.Message, err)
+	}
+}
+
+func TestTemplatesErrors(t *testing.T) {
+	dir := templateDir(t, map[string]string{"sms/default.body.tmpl": "{{.Message"})
+	defer os.RemoveAll(dir)
+	if _, err := LoadTemplates(dir); err == nil {
+		t.Error("expected an error for a template that can't be parsed")
+	}
+
+	dir = templateDir(t, map[string]string{"sms/default.body.tmpl": "{{.Missing}}"})
+	defer os.RemoveAll(dir)
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := Notification{Title: "CPU", Message: "CPU Usage: 95"}
+	rendered, err := templates.Render("sms", n)
+	if err == nil {
+		t.Error("expected an error for a field the notification doesn't have")
+	}
+	if rendered.Message != n.Message {
+		t.Errorf("the message is %q after a failed render, expected it to be kept", rendered.Message)
+	}
+}
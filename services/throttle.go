@@ -0,0 +1,155 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// ThrottleConfig is the rate limit configuration read from the "ratelimit" key in the server config,
+// a limit of 0 means unlimited
+type ThrottleConfig struct {
+	Window    int            `mapstructure:"window"`    // In seconds, the limits are per window
+	Service   int            `mapstructure:"service"`   // Notifications per service
+	Services  map[string]int `mapstructure:"services"`  // Notifications per service for specific services like "sms"
+	Recipient int            `mapstructure:"recipient"` // Notifications per contact and service, or per service without contacts
+	Dedup     int            `mapstructure:"dedup"`     // In seconds, identical notifications within it are dropped
+}
+
+// LoadThrottleConfig reads the rate limit configuration from the server config
+func LoadThrottleConfig() (ThrottleConfig, error) {
+	var c ThrottleConfig
+	if err := viper.UnmarshalKey("ratelimit", &c); err != nil {
+		return c, errors.Wrap(err, "error reading ratelimit config")
+	}
+	if c.Window <= 0 {
+		return c, errors.New("ratelimit config needs a window")
+	}
+	return c, nil
+}
+
+// Throttle rate limits the notifications per service and per recipient and drops duplicated notifications,
+// the amount of dropped notifications is told in the next notification that is sent by the service
+type Throttle struct {
+	Config ThrottleConfig
+
+	rw         *sync.RWMutex
+	sent       map[string][]time.Time // When notifications were sent per service and per recipient
+	last       map[string]time.Time   // When a notification was sent for the same client, alert, state and severity
+	suppressed map[string]int         // Dropped notifications per service since the last sent notification
+}
+
+// NewThrottle creates a throttle with the configuration
+func NewThrottle(config ThrottleConfig) *Throttle {
+	return &Throttle{
+		Config:     config,
+		rw:         new(sync.RWMutex),
+		sent:       map[string][]time.Time{},
+		last:       map[string]time.Time{},
+		suppressed: map[string]int{},
+	}
+}
+
+// Allow returns whether the notification should be sent by the service, the returned notification only
+// contains the contacts that are below their limit and tells how many notifications were dropped before it
+func (t *Throttle) Allow(service string, n Notification) (Notification, bool) {
+	now := time.Now()
+	t.rw.Lock()
+	defer t.rw.Unlock()
+	t.prune(now)
+
+	// A firing alert that changes severity isn't a duplicate
+	key := strings.Join([]string{service, n.ClientID.Hex(), n.AlertID.Hex(), n.State, n.Severity}, "|")
+	if last, ok := t.last[key]; ok && now.Sub(last) < time.Duration(t.Config.Dedup)*time.Second {
+		t.suppressed[service]++
+		return n, false
+	}
+
+	if limit := t.limit(service); limit > 0 && len(t.sent[service]) >= limit {
+		t.suppressed[service]++
+		return n, false
+	}
+
+	var recipients []string
+	if t.Config.Recipient > 0 && len(n.Contacts) == 0 {
+		// Without contacts the service sends to the recipients it was created with, like "sms:oncall" or
+		// "sms:46700000001", so they are limited together as a single recipient of the service
+		k := recipientKey(service, Contact{})
+		if len(t.sent[k]) >= t.Config.Recipient {
+			t.suppressed[service]++
+			return n, false
+		}
+		recipients = append(recipients, k)
+	} else if t.Config.Recipient > 0 {
+		var contacts []Contact
+		for _, c := range n.Contacts {
+			if k := recipientKey(service, c); len(t.sent[k]) < t.Config.Recipient {
+				contacts = append(contacts, c)
+				recipients = append(recipients, k)
+			}
+		}
+		if len(contacts) == 0 {
+			t.suppressed[service]++
+			return n, false
+		}
+		n.Contacts = contacts
+	}
+
+	t.last[key] = now
+	t.sent[service] = append(t.sent[service], now)
+	for _, k := range recipients {
+		t.sent[k] = append(t.sent[k], now)
+	}
+
+	if suppressed := t.suppressed[service]; suppressed > 0 {
+		n.Suppressed = suppressed
+		n.Message = fmt.Sprintf("%s\n\n%d notifications were suppressed", n.Message, suppressed)
+		delete(t.suppressed, service)
+	}
+	return n, true
+}
+
+// limit returns the limit for the service, a limit for the type of service like "sms" is used
+// for all services of that type like "sms:oncall"
+func (t *Throttle) limit(service string) int {
+	if limit, ok := t.Config.Services[service]; ok {
+		return limit
+	}
+	if i := strings.Index(service, ":"); i >= 0 {
+		if limit, ok := t.Config.Services[service[:i]]; ok {
+			return limit
+		}
+	}
+	return t.Config.Service
+}
+
+// prune removes everything that is outside of the windows
+func (t *Throttle) prune(now time.Time) {
+	window := time.Duration(t.Config.Window) * time.Second
+	for k, sent := range t.sent {
+		i := 0
+		for i < len(sent) && now.Sub(sent[i]) >= window {
+			i++
+		}
+		if i == len(sent) {
+			delete(t.sent, k)
+		} else {
+			t.sent[k] = sent[i:]
+		}
+	}
+
+	dedup := time.Duration(t.Config.Dedup) * time.Second
+	for k, last := range t.last {
+		if now.Sub(last) >= dedup {
+			delete(t.last, k)
+		}
+	}
+}
+
+func recipientKey(service string, c Contact) string {
+	return strings.Join([]string{service, c.Name, c.Phone, c.Email, c.Chat}, "|")
+}
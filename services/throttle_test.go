@@ -0,0 +1,133 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestThrottleAllow(t *testing.T) {
+	anna := Contact{Name: "Anna", Phone: "46700000001"}
+	bo := Contact{Name: "Bo", Phone: "46700000002"}
+	alert := bson.NewObjectId()
+	firing := Notification{AlertID: alert, State: "firing", Severity: "warning", Message: "CPU Usage: 95"}
+	critical := Notification{AlertID: alert, State: "firing", Severity: "critical", Message: "CPU Usage: 99"}
+	other := Notification{AlertID: bson.NewObjectId(), State: "firing", Severity: "warning", Message: "Memory Usage: 95"}
+
+	with := func(n Notification, contacts ...Contact) Notification {
+		n.Contacts = contacts
+		return n
+	}
+
+	type send struct {
+		service  string
+		n        Notification
+		allowed  bool
+		contacts []Contact // The contacts that are left, checked if allowed
+	}
+	tests := []struct {
+		name   string
+		config ThrottleConfig
+		sends  []send
+	}{
+		{
+			name:   "duplicates",
+			config: ThrottleConfig{Window: 60, Dedup: 300},
+			sends: []send{
+				{service: "email", n: firing, allowed: true},
+				{service: "email", n: firing},
+				{service: "sms", n: firing, allowed: true},
+				// A changed severity isn't a duplicate
+				{service: "email", n: critical, allowed: true},
+			},
+		},
+		{
+			name:   "service limit",
+			config: ThrottleConfig{Window: 60, Service: 2},
+			sends: []send{
+				{service: "email", n: firing, allowed: true},
+				{service: "email", n: other, allowed: true},
+				{service: "email", n: critical},
+				{service: "chat", n: critical, allowed: true},
+			},
+		},
+		{
+			name:   "service type limit",
+			config: ThrottleConfig{Window: 60, Service: 5, Services: map[string]int{"sms": 1}},
+			sends: []send{
+				{service: "sms:oncall", n: firing, allowed: true},
+				{service: "sms:oncall", n: other},
+				{service: "sms:dba", n: other, allowed: true},
+				{service: "email", n: other, allowed: true},
+				{service: "email", n: critical, allowed: true},
+			},
+		},
+		{
+			name:   "recipient limit",
+			config: ThrottleConfig{Window: 60, Recipient: 1},
+			sends: []send{
+				{service: "sms", n: with(firing, anna), allowed: true, contacts: []Contact{anna}},
+				{service: "sms", n: with(other, anna, bo), allowed: true, contacts: []Contact{bo}},
+				{service: "sms", n: with(critical, anna, bo)},
+				{service: "email", n: with(critical, anna, bo), allowed: true, contacts: []Contact{anna, bo}},
+			},
+		},
+		{
+			name:   "recipient limit without contacts",
+			config: ThrottleConfig{Window: 60, Recipient: 1},
+			sends: []send{
+				{service: "sms:oncall", n: firing, allowed: true},
+				{service: "sms:oncall", n: other},
+				{service: "sms:dba", n: other, allowed: true},
+				{service: "sms:46700000001", n: other, allowed: true},
+				{service: "sms:46700000001", n: critical},
+				// The contacts are limited apart from the configured recipients
+				{service: "sms:oncall", n: with(critical, anna), allowed: true, contacts: []Contact{anna}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		throttle := NewThrottle(test.config)
+		for i, s := range test.sends {
+			n, allowed := throttle.Allow(s.service, s.n)
+			if allowed != s.allowed {
+				t.Errorf("%s: send %d to %s: allowed is %t, expected %t", test.name, i+1, s.service, allowed, s.allowed)
+				continue
+			}
+			if allowed && !reflect.DeepEqual(n.Contacts, s.contacts) {
+				t.Errorf("%s: send %d to %s: contacts are %v, expected %v", test.name, i+1, s.service, n.Contacts, s.contacts)
+			}
+		}
+	}
+}
+
+func TestThrottleSuppressed(t *testing.T) {
+	throttle := NewThrottle(ThrottleConfig{Window: 60, Service: 1, Services: map[string]int{"chat": 3}})
+	n := Notification{AlertID: bson.NewObjectId(), State: "firing", Severity: "warning", Message: "CPU Usage: 95"}
+
+	throttle.Allow("email", n)
+	throttle.Allow("email", n)
+	throttle.Allow("email", n)
+
+	// The suppressed notifications are told in the next notification of the same service
+	sent, allowed := throttle.Allow("chat", n)
+	if !allowed || sent.Suppressed != 0 {
+		t.Fatalf("chat: allowed is %t with %d suppressed, expected it to be allowed without any", allowed, sent.Suppressed)
+	}
+
+	throttle.rw.Lock()
+	throttle.sent = map[string][]time.Time{}
+	throttle.rw.Unlock()
+
+	sent, allowed = throttle.Allow("email", n)
+	if !allowed || sent.Suppressed != 2 || !strings.HasSuffix(sent.Message, "2 notifications were suppressed") {
+		t.Errorf("email: allowed is %t with %d suppressed and the message %q", allowed, sent.Suppressed, sent.Message)
+	}
+	if sent, _ = throttle.Allow("chat", n); sent.Suppressed != 0 {
+		t.Errorf("chat: %d suppressed, expected none", sent.Suppressed)
+	}
+}
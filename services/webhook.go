@@ -0,0 +1,128 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// WebhookSignatureHeader is the header with the HMAC-SHA256 signature of the body when a secret is configured
+const WebhookSignatureHeader = "X-Keiwi-Signature"
+
+// WebhookConfig is the webhook configuration read from the "webhook" key in the server config
+type WebhookConfig struct {
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"`
+	Secret  string            `mapstructure:"secret"`
+	Timeout int               `mapstructure:"timeout"` // In seconds
+}
+
+// LoadWebhookConfig reads the webhook configuration from the server config
+func LoadWebhookConfig() (WebhookConfig, error) {
+	var c WebhookConfig
+	if err := viper.UnmarshalKey("webhook", &c); err != nil {
+		return c, errors.Wrap(err, "error reading webhook config")
+	}
+	if c.URL == "" {
+		return c, errors.New("webhook config needs an url")
+	}
+	// The defaults in the server config aren't used for the values missing from a partial "webhook" block
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	return c, nil
+}
+
+// WebhookPayload is the JSON body that is posted to the webhook
+type WebhookPayload struct {
+	AlertID    bson.ObjectId `json:"alert_id"`
+	ClientID   bson.ObjectId `json:"client_id"`
+	ClientIP   string        `json:"client_ip"`
+	Command    string        `json:"command"`
+	Group      string        `json:"group"`
+	Provider   string        `json:"provider"`
+	Title      string        `json:"title"`
+	Value      string        `json:"value"`
+	Threshold  string        `json:"threshold,omitempty"`
+	Message    string        `json:"message"`
+	Severity   string        `json:"severity"`
+	State      string        `json:"state"`
+	Timestamp  time.Time     `json:"timestamp"`
+	Contacts   []Contact     `json:"contacts,omitempty"`
+	Suppressed int           `json:"suppressed,omitempty"`
+}
+
+type Webhook struct {
+	Config WebhookConfig
+	Client *http.Client
+}
+
+func (Webhook) Name() string {
+	return "webhook"
+}
+
+func (w Webhook) Send(n Notification) error {
+	b, err := json.Marshal(WebhookPayload{
+		AlertID:    n.AlertID,
+		ClientID:   n.ClientID,
+		ClientIP:   n.ClientIP,
+		Command:    n.Command,
+		Group:      n.Group,
+		Provider:   n.Provider,
+		Title:      n.Title,
+		Value:      n.Value,
+		Threshold:  n.Threshold,
+		Message:    n.Message,
+		Severity:   n.Severity,
+		State:      n.State,
+		Timestamp:  n.Timestamp,
+		Contacts:   n.Contacts,
+		Suppressed: n.Suppressed,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error marshaling webhook payload")
+	}
+
+	// Failed posts are retried by the notification queue
+	return w.post(b)
+}
+
+func (w Webhook) post(body []byte) error {
+	req, err := http.NewRequest("POST", w.Config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Config.Headers {
+		req.Header.Set(k, v)
+	}
+	if w.Config.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.Config.Secret))
+		mac.Write(body)
+		req.Header.Set(WebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// webhookRequest is a request received by the webhook stand-in
+type webhookRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookServer starts a webhook that responds with the status, the received requests are sent to the returned channel
+func webhookServer(t *testing.T, status int) (*httptest.Server, <-chan webhookRequest) {
+	requests := make(chan webhookRequest, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		requests <- webhookRequest{header: r.Header, body: body}
+		w.WriteHeader(status)
+	}))
+	return s, requests
+}
+
+func TestWebhookSignature(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+	}{
+		{"with secret", "s3cret"},
+		{"without secret", ""},
+	}
+
+	for _, test := range tests {
+		s, requests := webhookServer(t, http.StatusNoContent)
+		w := NewServiceWebhook(WebhookConfig{URL: s.URL, Secret: test.secret, Headers: map[string]string{"X-Team": "ops"}, Timeout: 5})
+
+		err := w.Send(Notification{Title: "CPU", Message: "CPU Usage: 95", Severity: "critical", State: "firing"})
+		s.Close()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		r := <-requests
+		if ct := r.header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: content type is %q", test.name, ct)
+		}
+		if team := r.header.Get("X-Team"); team != "ops" {
+			t.Errorf("%s: the configured header is %q", test.name, team)
+		}
+
+		signature := r.header.Get(WebhookSignatureHeader)
+		if test.secret == "" {
+			if signature != "" {
+				t.Errorf("%s: unexpected signature %q", test.name, signature)
+			}
+			continue
+		}
+		// The receiver verifies the body with the shared secret
+		mac := hmac.New(sha256.New, []byte(test.secret))
+		mac.Write(r.body)
+		if expected := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != expected {
+			t.Errorf("%s: signature is %q, expected %q", test.name, signature, expected)
+		}
+
+		var payload WebhookPayload
+		if err := json.Unmarshal(r.body, &payload); err != nil {
+			t.Errorf("%s: invalid payload: %v", test.name, err)
+		} else if payload.Message != "CPU Usage: 95" || payload.Severity != "critical" {
+			t.Errorf("%s: unexpected payload %+v", test.name, payload)
+		}
+	}
+}
+
+func TestWebhookSendError(t *testing.T) {
+	s, _ := webhookServer(t, http.StatusInternalServerError)
+	defer s.Close()
+
+	w := NewServiceWebhook(WebhookConfig{URL: s.URL, Timeout: 5})
+	if err := w.Send(Notification{Title: "CPU"}); err == nil {
+		t.Error("expected an error when the webhook responds with 500")
+	}
+}
+
+func TestLoadWebhookConfig(t *testing.T) {
+	readConfig(t, `{"webhook":{"url":"https://example.com/hook","secret":"s3cret"}}`)
+	c, err := LoadWebhookConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (WebhookConfig{URL: "https://example.com/hook", Secret: "s3cret", Timeout: DefaultTimeout}); !reflect.DeepEqual(c, expected) {
+		t.Errorf("got %+v, expected %+v", c, expected)
+	}
+
+	readConfig(t, `{"webhook":{"timeout":5}}`)
+	if _, err := LoadWebhookConfig(); err == nil {
+		t.Error("expected an error without an url")
+	}
+}